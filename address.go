@@ -2,6 +2,7 @@ package posm
 
 import (
 	"fmt"
+	"strings"
 )
 
 // address contains address fields specific to OpenStreetMap
@@ -13,21 +14,89 @@ type address struct {
 	Cycleway      string `json:"cycleway,omitempty"`
 	Highway       string `json:"highway,omitempty"`
 	Path          string `json:"path,omitempty"`
+	HouseName     string `json:"house_name,omitempty"`
+	Building      string `json:"building,omitempty"`
+	Amenity       string `json:"amenity,omitempty"`
+	Shop          string `json:"shop,omitempty"`
+	Neighbourhood string `json:"neighbourhood,omitempty"`
+	Quarter       string `json:"quarter,omitempty"`
 	Suburb        string `json:"suburb,omitempty"`
+	Borough       string `json:"borough,omitempty"`
+	CityDistrict  string `json:"city_district,omitempty"`
 	City          string `json:"city,omitempty"`
 	Town          string `json:"town,omitempty"`
 	Village       string `json:"village,omitempty"`
 	Hamlet        string `json:"hamlet,omitempty"`
+	Municipality  string `json:"municipality,omitempty"`
 	County        string `json:"county,omitempty"`
 	Country       string `json:"country,omitempty"`
 	CountryCode   string `json:"country_code,omitempty"`
 	State         string `json:"state,omitempty"`
 	StateDistrict string `json:"state_district,omitempty"`
-	Postcode      string `json:"postcode,omitempty"`
+	Province      string `json:"province,omitempty"`
+	Region        string `json:"region,omitempty"`
+	// StateCode is the ISO 3166-2 subdivision code, e.g. "US-CA"
+	StateCode string `json:"ISO3166-2-lvl4,omitempty"`
+	Postcode  string `json:"postcode,omitempty"`
+}
+
+// Address is the structured form of a result's address.
+// Street and City are resolved from the alternative OSM fields the same way as the formatted string.
+type Address struct {
+	// HouseName is the name of the building, e.g. "Flatiron Building"
+	HouseName     string `json:"house_name,omitempty"`
+	HouseNumber   string `json:"house_number,omitempty"`
+	Street        string `json:"street,omitempty"`
+	Neighbourhood string `json:"neighbourhood,omitempty"`
+	Suburb        string `json:"suburb,omitempty"`
+	// CityDistrict is the borough or ward, e.g. "Camden" or "Shinjuku"
+	CityDistrict string `json:"city_district,omitempty"`
+	City         string `json:"city,omitempty"`
+	County       string `json:"county,omitempty"`
+	// State is the name as returned, the StateNames option only applies to the formatted address
+	State string `json:"state,omitempty"`
+	// StateCode is the ISO 3166-2 subdivision code, e.g. "US-CA"
+	StateCode   string `json:"state_code,omitempty"`
+	Postcode    string `json:"postcode,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	// POI is the amenity or shop name of the place
+	POI string `json:"poi,omitempty"`
+}
+
+// export converts the raw OSM address, returning nil when there is none
+func (a *address) export() *Address {
+	if a == nil {
+		return nil
+	}
+	return &Address{
+		HouseName:     firstNonEmpty(a.HouseName, a.Building),
+		HouseNumber:   a.HouseNumber,
+		Street:        a.getStreet(),
+		Neighbourhood: firstNonEmpty(a.Neighbourhood, a.Quarter),
+		Suburb:        a.Suburb,
+		CityDistrict:  firstNonEmpty(a.CityDistrict, a.Borough),
+		City:          a.getCity(),
+		County:        a.County,
+		State:         a.getState(),
+		StateCode:     a.getStateCode(),
+		Postcode:      a.Postcode,
+		Country:       a.Country,
+		CountryCode:   a.CountryCode,
+		POI:           firstNonEmpty(a.Amenity, a.Shop),
+	}
 }
 
 // getCity checks different fields for the city name
 func (a *address) getCity() string {
+	if a == nil {
+		return ""
+	}
+	return firstNonEmpty(a.getBaseCity(), a.Municipality, a.CityDistrict, a.Borough)
+}
+
+// getBaseCity checks the settlement fields only, see getAddress
+func (a *address) getBaseCity() string {
 	if a == nil {
 		return ""
 	}
@@ -46,6 +115,19 @@ func (a *address) getCity() string {
 
 // getStreet checks different fields for the street name
 func (a *address) getStreet() string {
+	if a == nil {
+		return ""
+	}
+	street := a.getBaseStreet()
+	if street == "" && a.HouseNumber != "" {
+		// numbered addresses without street names, e.g. Japanese blocks, are located by quarter
+		street = firstNonEmpty(a.Quarter, a.Neighbourhood)
+	}
+	return street
+}
+
+// getBaseStreet checks the road fields only, see getAddress
+func (a *address) getBaseStreet() string {
 	if a == nil {
 		return ""
 	}
@@ -66,19 +148,51 @@ func (a *address) getStreet() string {
 	return street
 }
 
+// getState checks different fields for the first-level subdivision
+func (a *address) getState() string {
+	if a == nil {
+		return ""
+	}
+	return firstNonEmpty(a.State, a.Province, a.Region)
+}
+
+// displayState is the state formatted with the StateNames option
+func (a *address) displayState() string {
+	if a == nil {
+		return ""
+	}
+	return formatState(a.CountryCode, a.getState())
+}
+
+// getStateCode returns the ISO 3166-2 code, deriving it from the state name when it is missing
+func (a *address) getStateCode() string {
+	if a == nil {
+		return ""
+	}
+	if a.StateCode != "" || a.CountryCode == "" {
+		return a.StateCode
+	}
+	if abbreviation, _, ok := lookupState(a.CountryCode, a.getState()); ok {
+		return strings.ToUpper(a.CountryCode) + "-" + abbreviation
+	}
+	return ""
+}
+
+// isCity uses the road and settlement fields only, the fallbacks of getStreet and getCity would change which results are cities
 func (a *address) isCity() bool {
 	if a == nil {
 		return false
 	}
-	return a.getStreet() == "" && a.getCity() != ""
+	return a.getBaseStreet() == "" && a.getBaseCity() != ""
 }
 
+// getAddress builds the text hashed into P-prefixed place IDs, keep it stable so stored IDs stay valid
 func (a *address) getAddress() string {
 	if a == nil {
 		return ""
 	}
-	address := a.getCity()
-	street := a.getStreet()
+	address := a.getBaseCity()
+	street := a.getBaseStreet()
 	if street != "" {
 		address = fmt.Sprintf("%s, %s", street, address)
 		if a.HouseNumber != "" {
@@ -93,3 +207,18 @@ func (a *address) getAddress() string {
 	}
 	return address
 }
+
+// dedupKey joins the lower-cased components with the state code and the country code, so neither the
+// StateNames option, the state spelling nor the country's address layout changes which results count as
+// repeats. States are only abbreviated within their own country, "Georgia" in ge stays a different state.
+func (a *Address) dedupKey(components ...string) string {
+	state := AbbreviateState(a.CountryCode, a.State)
+	if _, code, ok := strings.Cut(a.StateCode, "-"); ok {
+		state = code
+	}
+	parts := append(components, state, a.CountryCode)
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.Join(strings.Fields(part), " "))
+	}
+	return strings.Join(parts, "|")
+}
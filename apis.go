@@ -1,6 +1,7 @@
 package posm
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,109 +10,240 @@ import (
 const HEADQUARTER_LAT float64 = 37.7955
 const HEADQUARTER_LNG float64 = -122.3937
 
+// Autocomplete tag filters for addressable points and streets
+const (
+	autocompleteTagPoints  = "place:house,building,amenity,shop,tourism,leisure,office,highway"
+	autocompleteTagStreets = "highway"
+)
+
+// Reverse geocoding zoom levels for address and city detail
+const (
+	reverseZoomAddress = 18
+	reverseZoomCity    = 10
+)
+
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	// keyless clients talk to self-hosted servers that must not receive the LocationIQ key
+	keyless bool
 }
 
 // NewClient creates a new LocationIQ client
-func Init(accessToken string) {
+func Init(accessToken string, opts ...Option) {
+	o := newOptions(opts)
+	transport := o.transport()
 	locationIQAccessToken = accessToken
+	homeLocation = o.home
+	stateNames = o.stateNames
+	limiter = newRateLimiter(o.rateLimit)
+	cache = newResponseCache(o.cacheSize)
 	searchClient = &Client{
-		BaseURL:    "https://us1.locationiq.com/v1/search",
-		HTTPClient: &http.Client{},
+		BaseURL:    o.baseURL("/v1/search"),
+		HTTPClient: o.httpClient(EndpointSearch, transport),
 	}
 	autoCompleteClient = &Client{
 		BaseURL:    "https://api.locationiq.com/v1/autocomplete",
-		HTTPClient: &http.Client{},
+		HTTPClient: o.httpClient(EndpointAutocomplete, transport),
 	}
 	lookupClient = &Client{
-		BaseURL:    "https://us1.locationiq.com/v1/lookup",
-		HTTPClient: &http.Client{},
+		BaseURL:    o.baseURL("/v1/lookup"),
+		HTTPClient: o.httpClient(EndpointLookup, transport),
+	}
+	reverseClient = &Client{
+		BaseURL:    o.baseURL("/v1/reverse"),
+		HTTPClient: o.httpClient(EndpointReverse, transport),
+	}
+	nearbyClient = &Client{
+		BaseURL:    o.baseURL("/v1/nearby"),
+		HTTPClient: o.httpClient(EndpointNearby, transport),
+	}
+	timezoneClient = &Client{
+		BaseURL:    o.baseURL("/v1/timezone"),
+		HTTPClient: o.httpClient(EndpointTimezone, transport),
+	}
+	directionsClient = &Client{
+		BaseURL:    o.baseURL("/v1/directions"),
+		HTTPClient: o.httpClient(EndpointDirections, transport),
+	}
+	if o.routingURL != "" {
+		directionsClient.BaseURL = o.routingURL
+		directionsClient.keyless = true
+	}
+	matrixClient = &Client{
+		BaseURL:    o.baseURL("/v1/matrix"),
+		HTTPClient: o.httpClient(EndpointMatrix, transport),
+	}
+	if o.matrixURL != "" {
+		matrixClient.BaseURL = o.matrixURL
+		matrixClient.keyless = true
 	}
 }
 
-func GetStreetBySearch(text string) (*OsmStreet, error) {
-	var globalErr error
-	location, err := searchText(text)
+func GetStreetBySearch(text string, opts ...SearchOption) (*OsmStreet, error) {
+	return GetStreetBySearchContext(context.Background(), text, opts...)
+}
+
+// GetStreetBySearchContext is like GetStreetBySearch but uses ctx for the upstream request
+func GetStreetBySearchContext(ctx context.Context, text string, opts ...SearchOption) (*OsmStreet, error) {
+	options, err := newSearchOptions(opts)
 	if err != nil {
-		return nil, fmt.Errorf("searchText error: %w", err)
+		return nil, err
 	}
-	lat, lng, err := location.parseCoordinates()
+	location, err := searchText(ctx, text, options)
 	if err != nil {
-		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
+		return nil, fmt.Errorf("searchText error: %w", err)
 	}
-	return &OsmStreet{
-		PlaceID:     location.getPlaceID(),
-		Lat:         lat,
-		Lng:         lng,
-		DisplayName: location.DisplayName,
-		Address:     location.getStreetAddress(),
-	}, globalErr
+	return getOsmStreetFromLocationIQResponse(location)
 }
 
-func GetCityBySearch(text string) (*OsmCity, error) {
-	var globalErr error
-	location, err := searchText(text)
+func GetPointBySearch(text string, opts ...SearchOption) (*OsmPoint, error) {
+	return GetPointBySearchContext(context.Background(), text, opts...)
+}
+
+// GetPointBySearchContext is like GetPointBySearch but uses ctx for the upstream request
+func GetPointBySearchContext(ctx context.Context, text string, opts ...SearchOption) (*OsmPoint, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	location, err := searchText(ctx, text, options)
 	if err != nil {
 		return nil, fmt.Errorf("searchText error: %w", err)
 	}
-	lat, lng, err := location.parseCoordinates()
+	return getOsmPointFromLocationIQResponse(location)
+}
+
+func GetCityBySearch(text string, opts ...SearchOption) (*OsmCity, error) {
+	return GetCityBySearchContext(context.Background(), text, opts...)
+}
+
+// GetCityBySearchContext is like GetCityBySearch but uses ctx for the upstream request
+func GetCityBySearchContext(ctx context.Context, text string, opts ...SearchOption) (*OsmCity, error) {
+	options, err := newSearchOptions(opts)
 	if err != nil {
-		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
+		return nil, err
 	}
-	return &OsmCity{
-		PlaceID:     location.getPlaceID(),
-		Lat:         lat,
-		Lng:         lng,
-		DisplayName: location.DisplayName,
-		Address:     location.getCityAddress(),
-	}, globalErr
+	options.boundary = true
+	location, err := searchText(ctx, text, options)
+	if err != nil {
+		return nil, fmt.Errorf("searchText error: %w", err)
+	}
+	city, err := getOsmCityFromLocationIQResponse(location)
+	// a city without parsed coordinates would get the time zone of the fallback location
+	if _, _, coordErr := location.parseCoordinates(); coordErr == nil {
+		if tzErr := options.attachTimezones(ctx, city); tzErr != nil && err == nil {
+			err = tzErr
+		}
+	}
+	return city, err
 }
 
 func GetPointByLookup(tid string) (*OsmPoint, error) {
-	var globalErr error
-	point, err := lookupByOsmTID(tid)
+	return GetPointByLookupContext(context.Background(), tid)
+}
+
+// GetPointByLookupContext is like GetPointByLookup but uses ctx for the upstream request
+func GetPointByLookupContext(ctx context.Context, tid string) (*OsmPoint, error) {
+	point, err := lookupByOsmTID(ctx, tid, nil)
 	if err != nil {
 		return nil, fmt.Errorf("lookup error: %w", err)
 	}
-	lat, lng, err := point.parseCoordinates()
-	if err != nil {
-		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
-	}
-	return &OsmPoint{
-		PlaceID:          point.getPlaceID(),
-		Lat:              lat,
-		Lng:              lng,
-		DisplayName:      point.DisplayName,
-		Address:          point.getPointAddress(),
-		StreetSearchText: point.getStreetSearchText(),
-		CitySearchText:   point.getCitySearchText(),
-	}, globalErr
+	return getOsmPointFromLocationIQResponse(point)
 }
 
 func GetCityByLookup(tid string) (*OsmCity, error) {
-	var globalErr error
-	city, err := lookupByOsmTID(tid)
+	return GetCityByLookupContext(context.Background(), tid)
+}
+
+// GetCityByLookupContext is like GetCityByLookup but uses ctx for the upstream request
+func GetCityByLookupContext(ctx context.Context, tid string) (*OsmCity, error) {
+	city, err := lookupByOsmTID(ctx, tid, &SearchOptions{boundary: true})
 	if err != nil {
 		return nil, fmt.Errorf("lookup error: %w", err)
 	}
-	lat, lng, err := city.parseCoordinates()
+	return getOsmCityFromLocationIQResponse(city)
+}
+
+func GetPointByStructuredSearch(query StructuredQuery, opts ...SearchOption) (*OsmPoint, error) {
+	return GetPointByStructuredSearchContext(context.Background(), query, opts...)
+}
+
+// GetPointByStructuredSearchContext is like GetPointByStructuredSearch but uses ctx for the upstream request
+func GetPointByStructuredSearchContext(ctx context.Context, query StructuredQuery, opts ...SearchOption) (*OsmPoint, error) {
+	options, err := newSearchOptions(opts)
 	if err != nil {
-		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
+		return nil, err
 	}
-	return &OsmCity{
-		PlaceID:     city.getPlaceID(),
-		Lat:         lat,
-		Lng:         lng,
-		DisplayName: city.DisplayName,
-		Address:     city.getCityAddress(),
-	}, globalErr
+	location, err := structuredSearch(ctx, query, options)
+	if err != nil {
+		return nil, fmt.Errorf("structuredSearch error: %w", err)
+	}
+	return getOsmPointFromLocationIQResponse(location)
+}
+
+func GetStreetByStructuredSearch(query StructuredQuery, opts ...SearchOption) (*OsmStreet, error) {
+	return GetStreetByStructuredSearchContext(context.Background(), query, opts...)
+}
+
+// GetStreetByStructuredSearchContext is like GetStreetByStructuredSearch but uses ctx for the upstream request
+func GetStreetByStructuredSearchContext(ctx context.Context, query StructuredQuery, opts ...SearchOption) (*OsmStreet, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	location, err := structuredSearch(ctx, query, options)
+	if err != nil {
+		return nil, fmt.Errorf("structuredSearch error: %w", err)
+	}
+	return getOsmStreetFromLocationIQResponse(location)
+}
+
+func GetPointByCoordinates(lat, lng float64) (*OsmPoint, error) {
+	return GetPointByCoordinatesContext(context.Background(), lat, lng)
 }
 
-func GetPointsBySearch(text string) ([]*OsmPoint, error) {
+// GetPointByCoordinatesContext is like GetPointByCoordinates but uses ctx for the upstream request
+func GetPointByCoordinatesContext(ctx context.Context, lat, lng float64) (*OsmPoint, error) {
+	if err := (Coordinate{Lat: lat, Lng: lng}).validate(); err != nil {
+		return nil, err
+	}
+	location, err := reverse(ctx, lat, lng, reverseZoomAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reverse error: %w", err)
+	}
+	return getOsmPointFromLocationIQResponse(location)
+}
+
+func GetCityByCoordinates(lat, lng float64) (*OsmCity, error) {
+	return GetCityByCoordinatesContext(context.Background(), lat, lng)
+}
+
+// GetCityByCoordinatesContext is like GetCityByCoordinates but uses ctx for the upstream request
+func GetCityByCoordinatesContext(ctx context.Context, lat, lng float64) (*OsmCity, error) {
+	if err := (Coordinate{Lat: lat, Lng: lng}).validate(); err != nil {
+		return nil, err
+	}
+	location, err := reverse(ctx, lat, lng, reverseZoomCity, &SearchOptions{boundary: true})
+	if err != nil {
+		return nil, fmt.Errorf("reverse error: %w", err)
+	}
+	return getOsmCityFromLocationIQResponse(location)
+}
+
+func GetPointsBySearch(text string, opts ...SearchOption) ([]*OsmPoint, error) {
+	return GetPointsBySearchContext(context.Background(), text, opts...)
+}
+
+// GetPointsBySearchContext is like GetPointsBySearch but uses ctx for the upstream request
+func GetPointsBySearchContext(ctx context.Context, text string, opts ...SearchOption) ([]*OsmPoint, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	var globalErr error
-	locations, err := searchTextMany(text)
+	locations, err := searchTextMany(ctx, text, options)
 	if err != nil {
 		if isUnableToGeocode(err) {
 			return []*OsmPoint{}, nil
@@ -119,18 +251,15 @@ func GetPointsBySearch(text string) ([]*OsmPoint, error) {
 		return nil, fmt.Errorf("searchTextMany error: %w", err)
 	}
 	points := make([]*OsmPoint, 0)
-	normalizedSearch := strings.ToLower(strings.TrimSpace(text))
-	if len(normalizedSearch) > 3 {
-		normalizedSearch = normalizedSearch[:3]
-	}
+	lead := searchLead(text)
 	seenAddresses := make(map[string]struct{})
 	for _, location := range locations {
 		point, err := getOsmPointFromLocationIQResponse(&location)
 		if err == nil {
-			normalizedAddress := strings.ToLower(strings.TrimSpace(point.Address))
-			if normalizedSearch != "" && !strings.HasPrefix(normalizedAddress, normalizedSearch) {
+			if !point.matchesSearch(lead) {
 				continue
 			}
+			normalizedAddress := point.dedupKey()
 			if _, exists := seenAddresses[normalizedAddress]; exists {
 				continue
 			}
@@ -144,9 +273,19 @@ func GetPointsBySearch(text string) ([]*OsmPoint, error) {
 	return points, globalErr
 }
 
-func GetCitiesBySearch(text string) ([]*OsmCity, error) {
+func GetCitiesBySearch(text string, opts ...SearchOption) ([]*OsmCity, error) {
+	return GetCitiesBySearchContext(context.Background(), text, opts...)
+}
+
+// GetCitiesBySearchContext is like GetCitiesBySearch but uses ctx for the upstream request
+func GetCitiesBySearchContext(ctx context.Context, text string, opts ...SearchOption) ([]*OsmCity, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	options.boundary = true
 	var globalErr error
-	locations, err := searchTextMany(text)
+	locations, err := searchTextMany(ctx, text, options)
 	if err != nil {
 		return nil, fmt.Errorf("searchTextMany error: %w", err)
 	}
@@ -163,12 +302,24 @@ func GetCitiesBySearch(text string) ([]*OsmCity, error) {
 			globalErr = fmt.Errorf("getOsmCityFromLocationIQResponse error: %w", err)
 		}
 	}
+	if err := options.attachTimezones(ctx, cities...); err != nil {
+		globalErr = err
+	}
 	return cities, globalErr
 }
 
-func GetCitiesByAutocomplete(text string) ([]*OsmCity, error) {
+func GetCitiesByAutocomplete(text string, opts ...SearchOption) ([]*OsmCity, error) {
+	return GetCitiesByAutocompleteContext(context.Background(), text, opts...)
+}
+
+// GetCitiesByAutocompleteContext is like GetCitiesByAutocomplete but uses ctx for the upstream request
+func GetCitiesByAutocompleteContext(ctx context.Context, text string, opts ...SearchOption) ([]*OsmCity, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	var globalErr error
-	locations, err := autocomplete(text)
+	locations, err := autocomplete(ctx, text, "", options)
 	if err != nil {
 		if isUnableToGeocode(err) {
 			return []*OsmCity{}, nil
@@ -187,7 +338,7 @@ func GetCitiesByAutocomplete(text string) ([]*OsmCity, error) {
 		}
 		city, err := getOsmCityFromLocationIQResponse(&location)
 		if err == nil {
-			normalizedAddress := strings.ToLower(strings.TrimSpace(city.Address))
+			normalizedAddress := city.dedupKey()
 			if _, exists := seenAddresses[normalizedAddress]; exists {
 				continue
 			}
@@ -198,9 +349,92 @@ func GetCitiesByAutocomplete(text string) ([]*OsmCity, error) {
 			globalErr = fmt.Errorf("getOsmCityFromLocationIQResponse error: %w", err)
 		}
 	}
+	if err := options.attachTimezones(ctx, cities...); err != nil {
+		globalErr = err
+	}
 	return cities, globalErr
 }
 
+func GetPointsByAutocomplete(text string, opts ...SearchOption) ([]*OsmPoint, error) {
+	return GetPointsByAutocompleteContext(context.Background(), text, opts...)
+}
+
+// GetPointsByAutocompleteContext is like GetPointsByAutocomplete but uses ctx for the upstream request
+func GetPointsByAutocompleteContext(ctx context.Context, text string, opts ...SearchOption) ([]*OsmPoint, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var globalErr error
+	locations, err := autocomplete(ctx, text, autocompleteTagPoints, options)
+	if err != nil {
+		if isUnableToGeocode(err) {
+			return []*OsmPoint{}, nil
+		}
+		return nil, fmt.Errorf("autocomplete error: %w", err)
+	}
+	points := make([]*OsmPoint, 0)
+	seenAddresses := make(map[string]struct{})
+	for _, location := range locations {
+		if location.isCity() {
+			continue
+		}
+		point, err := getOsmPointFromLocationIQResponse(&location)
+		if err == nil {
+			normalizedAddress := point.dedupKey()
+			if _, exists := seenAddresses[normalizedAddress]; exists {
+				continue
+			}
+			seenAddresses[normalizedAddress] = struct{}{}
+			points = append(points, point)
+		} else {
+			// log the error and continue with other results
+			globalErr = fmt.Errorf("getOsmPointFromLocationIQResponse error: %w", err)
+		}
+	}
+	return points, globalErr
+}
+
+func GetStreetsByAutocomplete(text string, opts ...SearchOption) ([]*OsmStreet, error) {
+	return GetStreetsByAutocompleteContext(context.Background(), text, opts...)
+}
+
+// GetStreetsByAutocompleteContext is like GetStreetsByAutocomplete but uses ctx for the upstream request
+func GetStreetsByAutocompleteContext(ctx context.Context, text string, opts ...SearchOption) ([]*OsmStreet, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	var globalErr error
+	locations, err := autocomplete(ctx, text, autocompleteTagStreets, options)
+	if err != nil {
+		if isUnableToGeocode(err) {
+			return []*OsmStreet{}, nil
+		}
+		return nil, fmt.Errorf("autocomplete error: %w", err)
+	}
+	streets := make([]*OsmStreet, 0)
+	seenAddresses := make(map[string]struct{})
+	for _, location := range locations {
+		if location.Address.getStreet() == "" {
+			continue
+		}
+		street, err := getOsmStreetFromLocationIQResponse(&location)
+		if err == nil {
+			normalizedAddress := street.dedupKey()
+			if _, exists := seenAddresses[normalizedAddress]; exists {
+				continue
+			}
+			seenAddresses[normalizedAddress] = struct{}{}
+			streets = append(streets, street)
+		} else {
+			// log the error and continue with other results
+			globalErr = fmt.Errorf("getOsmStreetFromLocationIQResponse error: %w", err)
+		}
+	}
+	return streets, globalErr
+}
+
 func IsOsmPlace(placeID string) bool {
 	placeType := getPlaceType(placeID)
 	return placeType == PlaceTypeOsmNode ||
@@ -232,33 +466,92 @@ func getOsmPointFromLocationIQResponse(resp *locationIQResponse) (*OsmPoint, err
 	if err != nil {
 		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
 	}
+	bbox, err := parseBoundingBox(resp.BoundingBox)
+	if err != nil {
+		globalErr = fmt.Errorf("parseBoundingBox error: %w", err)
+	}
 	return &OsmPoint{
-		PlaceID:          resp.getPlaceID(),
-		Lat:              lat,
-		Lng:              lng,
-		DisplayName:      resp.DisplayName,
-		Address:          resp.getPointAddress(),
-		StreetSearchText: resp.getStreetSearchText(),
-		CitySearchText:   resp.getCitySearchText(),
+		PlaceID:           resp.getPlaceID(),
+		Lat:               lat,
+		Lng:               lng,
+		DisplayName:       resp.DisplayName,
+		Address:           resp.getPointAddress(),
+		StructuredAddress: resp.Address.export(),
+		StreetSearchText:  resp.getStreetSearchText(),
+		CitySearchText:    resp.getCitySearchText(),
+		BBox:              bbox,
+		Details:           resp.getDetails(),
+		Name:              resp.Name,
+		Category:          resp.getCategory(),
+		Distance:          resp.Distance,
 	}, globalErr
 }
 
-func getOsmCityFromLocationIQResponse(resp *locationIQResponse) (*OsmCity, error) {
+func getOsmStreetFromLocationIQResponse(resp *locationIQResponse) (*OsmStreet, error) {
 	var globalErr error
 	lat, lng, err := resp.parseCoordinates()
 	if err != nil {
 		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
 	}
+	bbox, err := parseBoundingBox(resp.BoundingBox)
+	if err != nil {
+		globalErr = fmt.Errorf("parseBoundingBox error: %w", err)
+	}
+	return &OsmStreet{
+		PlaceID:           resp.getPlaceID(),
+		Lat:               lat,
+		Lng:               lng,
+		DisplayName:       resp.DisplayName,
+		Address:           resp.getStreetAddress(),
+		StructuredAddress: resp.Address.export(),
+		BBox:              bbox,
+		Details:           resp.getDetails(),
+	}, globalErr
+}
 
+func getOsmCityFromLocationIQResponse(resp *locationIQResponse) (*OsmCity, error) {
+	var globalErr error
+	lat, lng, err := resp.parseCoordinates()
+	if err != nil {
+		globalErr = fmt.Errorf("parseCoordinates error: %w", err)
+	}
+	bbox, err := parseBoundingBox(resp.BoundingBox)
+	if err != nil {
+		globalErr = fmt.Errorf("parseBoundingBox error: %w", err)
+	}
+	boundary, err := parseBoundary(resp.GeoJSON)
+	if err != nil {
+		globalErr = fmt.Errorf("parseBoundary error: %w", err)
+	}
 	return &OsmCity{
-		PlaceID:     resp.getPlaceID(),
-		Lat:         lat,
-		Lng:         lng,
-		DisplayName: resp.DisplayName,
-		Address:     resp.getCityAddress(),
+		PlaceID:           resp.getPlaceID(),
+		Lat:               lat,
+		Lng:               lng,
+		DisplayName:       resp.DisplayName,
+		Address:           resp.getCityAddress(),
+		StructuredAddress: resp.Address.export(),
+		BBox:              bbox,
+		Details:           resp.getDetails(),
+		Boundary:          boundary,
 	}, globalErr
 }
 
+// structuredSearch runs a structured search and falls back to free text when it finds nothing
+func structuredSearch(ctx context.Context, query StructuredQuery, options *SearchOptions) (*locationIQResponse, error) {
+	text := query.String()
+	if text == "" {
+		return nil, fmt.Errorf("empty structured query")
+	}
+	results, err := searchStructured(ctx, query, options)
+	if err != nil {
+		return nil, fmt.Errorf("searchStructured error: %w", err)
+	}
+	if len(results) > 0 {
+		return pickLocation(results)
+	}
+	return searchText(ctx, text, options)
+}
+
 func isUnableToGeocode(err error) bool {
 	return strings.Contains(strings.ToLower(err.Error()), "unable to geocode")
 }
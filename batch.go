@@ -0,0 +1,375 @@
+package posm
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Batch file formats
+const (
+	BatchFormatCSV   = "csv"
+	BatchFormatJSONL = "jsonl"
+)
+
+// DefaultBatchWorkers is the worker pool size used when BatchOptions.Workers is zero
+const DefaultBatchWorkers = 4
+
+var batchCSVHeader = []string{"id", "place_id", "lat", "lng", "address", "error"}
+
+// BatchRecord is one input record, either a free-text Query or structured components.
+// CSV input needs a header row naming these columns.
+type BatchRecord struct {
+	ID         string `json:"id"`
+	Query      string `json:"query,omitempty"`
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	County     string `json:"county,omitempty"`
+	State      string `json:"state,omitempty"`
+	Country    string `json:"country,omitempty"`
+	PostalCode string `json:"postalcode,omitempty"`
+}
+
+// BatchResult is one output record
+type BatchResult struct {
+	ID      string  `json:"id"`
+	PlaceID string  `json:"place_id,omitempty"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+	Address string  `json:"address,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	err     error
+}
+
+// BatchOptions configures GeocodeBatch
+type BatchOptions struct {
+	// Format of both input and output, BatchFormatCSV or BatchFormatJSONL
+	Format string
+	// Workers is the number of records geocoded concurrently
+	Workers int
+	// CheckpointPath records finished IDs so an interrupted run can resume, empty disables it
+	CheckpointPath string
+	// SearchOptions are passed to every search
+	SearchOptions []SearchOption
+	// SkipHeader leaves out the CSV header, for appending to output that already has one.
+	// Files are checked automatically and only get a header when empty.
+	SkipHeader bool
+}
+
+// BatchStats summarizes a GeocodeBatch run
+type BatchStats struct {
+	Geocoded int
+	Failed   int
+	Skipped  int
+}
+
+// GeocodeBatch streams records from in, geocodes them with a bounded worker pool and writes
+// one result per record to out. Records already in the checkpoint are skipped, and records
+// cut short by cancellation or an exhausted budget are left for the next run. Records that
+// failed with a retryable error are written but not checkpointed, so the next run retries them.
+func GeocodeBatch(ctx context.Context, in io.Reader, out io.Writer, opts BatchOptions) (BatchStats, error) {
+	var stats BatchStats
+	if opts.Format != BatchFormatCSV && opts.Format != BatchFormatJSONL {
+		return stats, fmt.Errorf("unsupported batch format %q", opts.Format)
+	}
+	if _, err := newSearchOptions(opts.SearchOptions); err != nil {
+		return stats, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	done, err := readCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return stats, err
+	}
+	var checkpoint *os.File
+	if opts.CheckpointPath != "" {
+		checkpoint, err = os.OpenFile(opts.CheckpointPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return stats, fmt.Errorf("failed to open checkpoint: %w", err)
+		}
+		defer checkpoint.Close()
+	}
+	writer, err := newBatchWriter(out, opts.Format, !opts.SkipHeader && isEmptyOutput(out))
+	if err != nil {
+		return stats, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records := make(chan BatchRecord)
+	results := make(chan BatchResult)
+	var readErr, stopErr error
+
+	go func() {
+		defer close(records)
+		readErr = readBatchRecords(ctx, in, opts.Format, func(record BatchRecord) bool {
+			if _, ok := done[record.ID]; ok {
+				stats.Skipped++
+				return true
+			}
+			select {
+			case records <- record:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range records {
+				results <- geocodeBatchRecord(ctx, record, opts.SearchOptions)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		if stopErr != nil {
+			continue
+		}
+		if result.err != nil && isBatchStopError(ctx, result.err) {
+			stopErr = fmt.Errorf("batch stopped at record %q: %w", result.ID, result.err)
+			cancel()
+			continue
+		}
+		if err := writer.write(result); err != nil {
+			stopErr = fmt.Errorf("failed to write result: %w", err)
+			cancel()
+			continue
+		}
+		if checkpoint != nil && (result.err == nil || !isRetryableBatchError(result.err)) {
+			if _, err := fmt.Fprintln(checkpoint, result.ID); err != nil {
+				stopErr = fmt.Errorf("failed to write checkpoint: %w", err)
+				cancel()
+				continue
+			}
+		}
+		if result.Error != "" {
+			stats.Failed++
+		} else {
+			stats.Geocoded++
+		}
+	}
+	if err := writer.flush(); err != nil && stopErr == nil {
+		stopErr = fmt.Errorf("failed to write result: %w", err)
+	}
+	if stopErr != nil {
+		return stats, stopErr
+	}
+	if readErr != nil {
+		return stats, readErr
+	}
+	return stats, ctx.Err()
+}
+
+// isBatchStopError reports failures that would hit every remaining record
+func isBatchStopError(ctx context.Context, err error) bool {
+	return ctx.Err() != nil ||
+		errors.Is(err, ErrBudgetExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// isRetryableBatchError reports failures that a later run may not hit, like network errors,
+// rate limiting and server errors. Their records are left out of the checkpoint.
+func isRetryableBatchError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "non-200 response: 429") || strings.Contains(message, "non-200 response: 5")
+}
+
+func geocodeBatchRecord(ctx context.Context, record BatchRecord, opts []SearchOption) BatchResult {
+	result := BatchResult{ID: record.ID}
+	var point *OsmPoint
+	var err error
+	if strings.TrimSpace(record.Query) != "" {
+		point, err = GetPointBySearchContext(ctx, record.Query, opts...)
+	} else {
+		point, err = GetPointByStructuredSearchContext(ctx, StructuredQuery{
+			Street:     record.Street,
+			City:       record.City,
+			County:     record.County,
+			State:      record.State,
+			Country:    record.Country,
+			PostalCode: record.PostalCode,
+		}, opts...)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.err = err
+		return result
+	}
+	result.PlaceID = point.PlaceID
+	result.Lat = point.Lat
+	result.Lng = point.Lng
+	result.Address = point.Address
+	return result
+}
+
+// isEmptyOutput reports whether out is a file without content yet, other writers are assumed empty
+func isEmptyOutput(out io.Writer) bool {
+	file, ok := out.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return true
+	}
+	info, err := file.Stat()
+	return err != nil || info.Size() == 0
+}
+
+// readBatchRecords decodes records and passes them to emit until it returns false
+func readBatchRecords(ctx context.Context, in io.Reader, format string, emit func(BatchRecord) bool) error {
+	line := 0
+	next := func(record BatchRecord) bool {
+		line++
+		if record.ID == "" {
+			record.ID = strconv.Itoa(line)
+		}
+		return emit(record)
+	}
+	if format == BatchFormatJSONL {
+		decoder := json.NewDecoder(in)
+		for ctx.Err() == nil {
+			var record BatchRecord
+			if err := decoder.Decode(&record); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to decode record %d: %w", line+1, err)
+			}
+			if !next(record) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for ctx.Err() == nil {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV record %d: %w", line+1, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		if !next(BatchRecord{
+			ID:         field("id"),
+			Query:      field("query"),
+			Street:     field("street"),
+			City:       field("city"),
+			County:     field("county"),
+			State:      field("state"),
+			Country:    field("country"),
+			PostalCode: field("postalcode"),
+		}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func readCheckpoint(path string) (map[string]struct{}, error) {
+	done := make(map[string]struct{})
+	if path == "" {
+		return done, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if id := scanner.Text(); id != "" {
+			done[id] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return done, nil
+}
+
+type batchWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newBatchWriter(out io.Writer, format string, writeHeader bool) (*batchWriter, error) {
+	if format == BatchFormatJSONL {
+		return &batchWriter{json: json.NewEncoder(out)}, nil
+	}
+	w := &batchWriter{csv: csv.NewWriter(out)}
+	if writeHeader {
+		if err := w.csv.Write(batchCSVHeader); err != nil {
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// write outputs one result and flushes it so the checkpoint never runs ahead of the output
+func (w *batchWriter) write(result BatchResult) error {
+	if w.json != nil {
+		return w.json.Encode(result)
+	}
+	row := []string{result.ID, result.PlaceID, "", "", result.Address, result.Error}
+	if result.Error == "" {
+		row[2] = strconv.FormatFloat(result.Lat, 'f', -1, 64)
+		row[3] = strconv.FormatFloat(result.Lng, 'f', -1, 64)
+	}
+	if err := w.csv.Write(row); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func (w *batchWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
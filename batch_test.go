@@ -0,0 +1,193 @@
+package posm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func batchTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case strings.HasPrefix(q.Get("q"), "1 Main"):
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"node","osm_id":"1","display_name":"1 Main St","lat":"10","lon":"20","address":{"house_number":"1","road":"Main St","city":"San Jose","state":"CA"}} ]`)
+		case q.Get("q") == "One Market Plaza":
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"way","osm_id":"3","display_name":"One Market Plaza","lat":"37.79","lon":"-122.39","address":{"house_number":"1","road":"Market Plaza","city":"San Francisco","state":"CA","country_code":"us"}} ]`)
+		case q.Get("q") == "Null Island":
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"node","osm_id":"4","display_name":"Null Island","lat":"0","lon":"0","address":{"country_code":"xx"}} ]`)
+		case q.Get("street") == "2 Oak Ave":
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"node","osm_id":"2","display_name":"2 Oak Ave","lat":"11","lon":"21","address":{"house_number":"2","road":"Oak Ave","city":"Oakland","state":"CA"}} ]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGeocodeBatchCSV(t *testing.T) {
+	server := batchTestServer()
+	defer server.Close()
+	setupClientsForServer(server)
+
+	in := "id,query,street,city\na,1 Main St,,\nb,,2 Oak Ave,Oakland\nc,nowhere,,\n"
+	var out bytes.Buffer
+	stats, err := GeocodeBatch(context.Background(), strings.NewReader(in), &out, BatchOptions{Format: BatchFormatCSV, Workers: 2})
+	if err != nil {
+		t.Fatalf("GeocodeBatch error: %v", err)
+	}
+	if stats.Geocoded != 2 || stats.Failed != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || lines[0] != "id,place_id,lat,lng,address,error" {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "a,N1,10,20,\"1 Main St, San Jose, CA\",") || !strings.Contains(out.String(), "b,N2,11,21,") {
+		t.Fatalf("missing geocoded rows:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "c,,,,,searchText error: no results found") {
+		t.Fatalf("missing error row:\n%s", out.String())
+	}
+}
+
+func TestGeocodeBatchResume(t *testing.T) {
+	server := batchTestServer()
+	defer server.Close()
+	setupClientsForServer(server)
+
+	tracker, err := NewUsageTracker("", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetUsageTracker(tracker)
+	defer SetUsageTracker(nil)
+
+	in := `{"id":"a","query":"1 Main St"}
+{"id":"b","street":"2 Oak Ave","city":"Oakland"}
+`
+	checkpoint := filepath.Join(t.TempDir(), "run.checkpoint")
+	opts := BatchOptions{Format: BatchFormatJSONL, Workers: 1, CheckpointPath: checkpoint}
+	var out bytes.Buffer
+	stats, err := GeocodeBatch(context.Background(), strings.NewReader(in), &out, opts)
+	if !errors.Is(err, ErrBudgetExceeded) || stats.Geocoded != 1 {
+		t.Fatalf("first run should stop on the budget after one record: stats=%+v err=%v", stats, err)
+	}
+
+	SetUsageTracker(nil)
+	stats, err = GeocodeBatch(context.Background(), strings.NewReader(in), &out, opts)
+	if err != nil || stats.Skipped != 1 || stats.Geocoded != 1 {
+		t.Fatalf("resumed run should only geocode the remaining record: stats=%+v err=%v", stats, err)
+	}
+
+	decoder := json.NewDecoder(&out)
+	ids := []string{}
+	for decoder.More() {
+		var result BatchResult
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Error != "" {
+			t.Fatalf("unexpected error result: %+v", result)
+		}
+		ids = append(ids, result.ID)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("each record should be written exactly once, got %v", ids)
+	}
+
+	if _, err := GeocodeBatch(context.Background(), strings.NewReader(in), &out, BatchOptions{Format: "xml"}); err == nil {
+		t.Fatalf("unknown formats should be rejected")
+	}
+}
+
+func TestGeocodeBatchQueries(t *testing.T) {
+	server := batchTestServer()
+	defer server.Close()
+	setupClientsForServer(server)
+
+	// queries that do not prefix the formatted address are still geocoded
+	in := `{"id":"a","query":"One Market Plaza"}
+{"id":"b","query":"Null Island"}
+`
+	var out bytes.Buffer
+	stats, err := GeocodeBatch(context.Background(), strings.NewReader(in), &out, BatchOptions{Format: BatchFormatJSONL, Workers: 1})
+	if err != nil || stats.Geocoded != 2 {
+		t.Fatalf("unexpected stats=%+v err=%v\n%s", stats, err, out.String())
+	}
+	if !strings.Contains(out.String(), `"place_id":"W3"`) || !strings.Contains(out.String(), `"place_id":"N4","lat":0,"lng":0`) {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestGeocodeBatchCSVAppend(t *testing.T) {
+	server := batchTestServer()
+	defer server.Close()
+	setupClientsForServer(server)
+
+	// a previous run wrote the header and a row but stopped before its checkpoint was written
+	path := filepath.Join(t.TempDir(), "out.csv")
+	if err := os.WriteFile(path, []byte("id,place_id,lat,lng,address,error\nx,N9,1,2,Somewhere,\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "id,query\na,1 Main St\n"
+	_, err = GeocodeBatch(context.Background(), strings.NewReader(in), out, BatchOptions{Format: BatchFormatCSV, CheckpointPath: path + ".checkpoint"})
+	out.Close()
+	if err != nil {
+		t.Fatalf("GeocodeBatch error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "id,place_id") != 1 || !strings.Contains(string(data), "a,N1,10,20,") {
+		t.Fatalf("appended output should keep a single header:\n%s", data)
+	}
+
+	var buffer bytes.Buffer
+	if _, err := GeocodeBatch(context.Background(), strings.NewReader(in), &buffer, BatchOptions{Format: BatchFormatCSV, SkipHeader: true}); err != nil || strings.Contains(buffer.String(), "id,place_id") {
+		t.Fatalf("SkipHeader should leave out the header: %v\n%s", err, buffer.String())
+	}
+}
+
+func TestGeocodeBatchRetriesTransientFailures(t *testing.T) {
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "1 Main St":
+			if failing {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"node","osm_id":"1","display_name":"1 Main St","lat":"10","lon":"20","address":{"house_number":"1","road":"Main St","city":"San Jose","state":"CA"}} ]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	in := "id,query\na,1 Main St\nb,nowhere\n"
+	opts := BatchOptions{Format: BatchFormatCSV, Workers: 1, CheckpointPath: filepath.Join(t.TempDir(), "run.checkpoint")}
+	var out bytes.Buffer
+	stats, err := GeocodeBatch(context.Background(), strings.NewReader(in), &out, opts)
+	if err != nil || stats.Failed != 2 {
+		t.Fatalf("unexpected first run: stats=%+v err=%v", stats, err)
+	}
+
+	// the 503 is retried, the record without results is not
+	failing = false
+	out.Reset()
+	stats, err = GeocodeBatch(context.Background(), strings.NewReader(in), &out, opts)
+	if err != nil || stats.Geocoded != 1 || stats.Skipped != 1 || !strings.Contains(out.String(), "a,N1,10,20,") {
+		t.Fatalf("resumed run should retry the transient failure: stats=%+v err=%v\n%s", stats, err, out.String())
+	}
+}
@@ -0,0 +1,91 @@
+package posm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// metersPerDegreeLat is the length of one degree of latitude
+const metersPerDegreeLat = 111320.0
+
+// BBox is a bounding box in degrees, boxes crossing the antimeridian are not supported
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+func (b BBox) validate() error {
+	if b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 {
+		return fmt.Errorf("coordinates out of range: %+v", b)
+	}
+	if b.MinLat >= b.MaxLat || b.MinLng >= b.MaxLng {
+		return fmt.Errorf("min must be below max: %+v", b)
+	}
+	return nil
+}
+
+// viewbox formats the box as LocationIQ's "min_lon,min_lat,max_lon,max_lat"
+func (b BBox) viewbox() string {
+	return fmt.Sprintf("%s,%s,%s,%s",
+		strconv.FormatFloat(b.MinLng, 'f', -1, 64),
+		strconv.FormatFloat(b.MinLat, 'f', -1, 64),
+		strconv.FormatFloat(b.MaxLng, 'f', -1, 64),
+		strconv.FormatFloat(b.MaxLat, 'f', -1, 64))
+}
+
+// Contains reports whether the point lies inside or on the edge of the box
+func (b BBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// Union returns the smallest box covering both boxes
+func (b BBox) Union(other BBox) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, other.MinLat),
+		MinLng: math.Min(b.MinLng, other.MinLng),
+		MaxLat: math.Max(b.MaxLat, other.MaxLat),
+		MaxLng: math.Max(b.MaxLng, other.MaxLng),
+	}
+}
+
+// Center returns the midpoint of the box
+func (b BBox) Center() Coordinate {
+	return Coordinate{Lat: (b.MinLat + b.MaxLat) / 2, Lng: (b.MinLng + b.MaxLng) / 2}
+}
+
+// Expand grows the box by meters on every side, clamped to valid coordinates
+func (b BBox) Expand(meters float64) BBox {
+	dLat := meters / metersPerDegreeLat
+	dLng := 180.0
+	if cos := math.Cos(b.Center().Lat * math.Pi / 180); cos > 1e-9 {
+		dLng = dLat / cos
+	}
+	return BBox{
+		MinLat: math.Max(-90, b.MinLat-dLat),
+		MinLng: math.Max(-180, b.MinLng-dLng),
+		MaxLat: math.Min(90, b.MaxLat+dLat),
+		MaxLng: math.Min(180, b.MaxLng+dLng),
+	}
+}
+
+// parseBoundingBox decodes LocationIQ's ["min_lat", "max_lat", "min_lon", "max_lon"]
+func parseBoundingBox(values []string) (*BBox, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("bounding box needs 4 values, got %d", len(values))
+	}
+	var parsed [4]float64
+	for i, value := range values {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = f
+	}
+	return &BBox{MinLat: parsed[0], MaxLat: parsed[1], MinLng: parsed[2], MaxLng: parsed[3]}, nil
+}
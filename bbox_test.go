@@ -0,0 +1,54 @@
+package posm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBBoxHelpers(t *testing.T) {
+	box := BBox{MinLat: 37.7, MinLng: -122.5, MaxLat: 37.8, MaxLng: -122.4}
+	if !box.Contains(37.75, -122.45) || box.Contains(37.9, -122.45) {
+		t.Fatalf("Contains gave wrong answer")
+	}
+	union := box.Union(BBox{MinLat: 37.6, MinLng: -122.3, MaxLat: 37.65, MaxLng: -122.2})
+	if union != (BBox{MinLat: 37.6, MinLng: -122.5, MaxLat: 37.8, MaxLng: -122.2}) {
+		t.Fatalf("Union = %+v", union)
+	}
+	if center := box.Center(); math.Abs(center.Lat-37.75) > 1e-9 || math.Abs(center.Lng+122.45) > 1e-9 {
+		t.Fatalf("Center = %+v", center)
+	}
+	expanded := box.Expand(1000)
+	if grown := distanceMeters(Coordinate{Lat: box.MaxLat, Lng: -122.45}, Coordinate{Lat: expanded.MaxLat, Lng: -122.45}); math.Abs(grown-1000) > 5 {
+		t.Fatalf("Expand should grow latitude by 1000m, got %v", grown)
+	}
+	if grown := distanceMeters(Coordinate{Lat: 37.75, Lng: box.MaxLng}, Coordinate{Lat: 37.75, Lng: expanded.MaxLng}); math.Abs(grown-1000) > 5 {
+		t.Fatalf("Expand should grow longitude by 1000m, got %v", grown)
+	}
+	if polar := (BBox{MinLat: 89.99, MinLng: 0, MaxLat: 90, MaxLng: 1}).Expand(10000); polar.MaxLat != 90 || polar.MinLng != -180 {
+		t.Fatalf("Expand should clamp near the pole: %+v", polar)
+	}
+}
+
+func TestResultBBox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[ {"osm_type":"way","osm_id":"1","display_name":"Market St","lat":"37.79","lon":"-122.40","boundingbox":["37.77","37.80","-122.42","-122.39"],"address":{"road":"Market St","city":"San Francisco","state":"CA"}} ]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	street, err := GetStreetBySearch("market")
+	if err != nil || street.BBox == nil || *street.BBox != (BBox{MinLat: 37.77, MinLng: -122.42, MaxLat: 37.80, MaxLng: -122.39}) {
+		t.Fatalf("street bbox not decoded: %+v %v", street, err)
+	}
+	points, err := GetPointsBySearch("market")
+	if err != nil || len(points) != 1 || points[0].BBox == nil || !points[0].BBox.Contains(points[0].Lat, points[0].Lng) {
+		t.Fatalf("point bbox not decoded: %+v %v", points, err)
+	}
+
+	if _, err := parseBoundingBox([]string{"1", "2"}); err == nil {
+		t.Fatalf("short bounding boxes should be rejected")
+	}
+}
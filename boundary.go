@@ -0,0 +1,179 @@
+package posm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// GeoJSON geometry types used for boundaries
+const (
+	GeometryPolygon      = "Polygon"
+	GeometryMultiPolygon = "MultiPolygon"
+)
+
+// Boundary is the outline of a place as a GeoJSON Polygon or MultiPolygon.
+// Polygons always holds polygons -> rings -> [lng, lat] positions, a Polygon has exactly one.
+type Boundary struct {
+	Type     string
+	Polygons [][][][2]float64
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// parseBoundary decodes a GeoJSON geometry, returning nil for geometries that are not areas
+func parseBoundary(raw json.RawMessage) (*Boundary, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var geometry geoJSONGeometry
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return nil, fmt.Errorf("failed to decode geojson: %w", err)
+	}
+	switch geometry.Type {
+	case GeometryPolygon:
+		var polygon [][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("failed to decode polygon: %w", err)
+		}
+		return &Boundary{Type: GeometryPolygon, Polygons: [][][][2]float64{polygon}}, nil
+	case GeometryMultiPolygon:
+		var polygons [][][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("failed to decode multipolygon: %w", err)
+		}
+		return &Boundary{Type: GeometryMultiPolygon, Polygons: polygons}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (b *Boundary) UnmarshalJSON(data []byte) error {
+	parsed, err := parseBoundary(data)
+	if err != nil {
+		return err
+	}
+	if parsed == nil {
+		return fmt.Errorf("geometry is not a polygon or multipolygon")
+	}
+	*b = *parsed
+	return nil
+}
+
+// MarshalJSON encodes the boundary as a GeoJSON geometry
+func (b Boundary) MarshalJSON() ([]byte, error) {
+	var coordinates any = b.Polygons
+	if b.Type == GeometryPolygon && len(b.Polygons) == 1 {
+		coordinates = b.Polygons[0]
+	}
+	return json.Marshal(struct {
+		Type        string `json:"type"`
+		Coordinates any    `json:"coordinates"`
+	}{Type: b.Type, Coordinates: coordinates})
+}
+
+// Simplify returns a copy with each ring reduced by Douglas-Peucker, tolerance is in degrees.
+// Rings keep at least four positions so they stay valid GeoJSON.
+func (b *Boundary) Simplify(tolerance float64) *Boundary {
+	if b == nil {
+		return nil
+	}
+	simplified := &Boundary{Type: b.Type, Polygons: make([][][][2]float64, len(b.Polygons))}
+	for i, polygon := range b.Polygons {
+		rings := make([][][2]float64, len(polygon))
+		for j, ring := range polygon {
+			rings[j] = simplifyRing(ring, tolerance)
+		}
+		simplified.Polygons[i] = rings
+	}
+	return simplified
+}
+
+func simplifyRing(ring [][2]float64, tolerance float64) [][2]float64 {
+	if tolerance <= 0 || len(ring) <= 4 {
+		return append([][2]float64(nil), ring...)
+	}
+	keep := make([]bool, len(ring))
+	keep[0], keep[len(ring)-1] = true, true
+	// a closed ring starts and ends on the same position, so split it at the farthest vertex
+	far, farDistance := 0, -1.0
+	for i := 1; i < len(ring)-1; i++ {
+		if d := math.Hypot(ring[i][0]-ring[0][0], ring[i][1]-ring[0][1]); d > farDistance {
+			far, farDistance = i, d
+		}
+	}
+	keep[far] = true
+	douglasPeucker(ring, 0, far, tolerance, keep)
+	douglasPeucker(ring, far, len(ring)-1, tolerance, keep)
+
+	result := make([][2]float64, 0, len(ring))
+	for i, position := range ring {
+		if keep[i] {
+			result = append(result, position)
+		}
+	}
+	if len(result) < 4 {
+		return append([][2]float64(nil), ring...)
+	}
+	return result
+}
+
+func douglasPeucker(points [][2]float64, start, end int, tolerance float64, keep []bool) {
+	if end <= start+1 {
+		return
+	}
+	index, maxDistance := -1, tolerance
+	for i := start + 1; i < end; i++ {
+		if d := segmentDistance(points[i], points[start], points[end]); d > maxDistance {
+			index, maxDistance = i, d
+		}
+	}
+	if index < 0 {
+		return
+	}
+	keep[index] = true
+	douglasPeucker(points, start, index, tolerance, keep)
+	douglasPeucker(points, index, end, tolerance, keep)
+}
+
+// segmentDistance is the planar distance from p to the segment a-b
+func segmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+func GetCityBoundary(placeID string, tolerance float64) (*Boundary, error) {
+	return GetCityBoundaryContext(context.Background(), placeID, tolerance)
+}
+
+// GetCityBoundaryContext is like GetCityBoundary but uses ctx for the upstream request.
+// A positive tolerance in degrees simplifies the outline.
+func GetCityBoundaryContext(ctx context.Context, placeID string, tolerance float64) (*Boundary, error) {
+	if !IsOsmPlace(placeID) {
+		return nil, fmt.Errorf("invalid OSM place ID %q", placeID)
+	}
+	if tolerance < 0 {
+		return nil, fmt.Errorf("invalid tolerance %v: must not be negative", tolerance)
+	}
+	location, err := lookupByOsmTID(ctx, placeID, &SearchOptions{boundary: true})
+	if err != nil {
+		return nil, fmt.Errorf("lookup error: %w", err)
+	}
+	boundary, err := parseBoundary(location.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+	if boundary == nil {
+		return nil, fmt.Errorf("no boundary for %s", placeID)
+	}
+	return boundary.Simplify(tolerance), nil
+}
@@ -0,0 +1,67 @@
+package posm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCityBoundary(t *testing.T) {
+	polygon := `{"type":"Polygon","coordinates":[[[0,0],[0.5,0.001],[1,0],[1,1],[0,1],[0,0]]]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("polygon_geojson") != "1" {
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"relation","osm_id":"1","display_name":"No polygon","lat":"0.5","lon":"0.5","address":{"city":"Square","state":"ST"}} ]`)
+			return
+		}
+		if q.Get("osm_ids") == "N2" {
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"node","osm_id":"2","display_name":"Node city","lat":"0.5","lon":"0.5","geojson":{"type":"Point","coordinates":[0.5,0.5]},"address":{"city":"Dot","state":"ST"}} ]`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `[ {"osm_type":"relation","osm_id":"1","display_name":"Square","lat":"0.5","lon":"0.5","geojson":%s,"address":{"city":"Square","state":"ST"}} ]`, polygon)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	city, err := GetCityBySearch("square")
+	if err != nil || city.Boundary == nil || city.Boundary.Type != GeometryPolygon || len(city.Boundary.Polygons[0][0]) != 6 {
+		t.Fatalf("GetCityBySearch should include the boundary: %+v %v", city, err)
+	}
+	street, err := GetStreetBySearch("square")
+	if err != nil || street == nil {
+		t.Fatalf("GetStreetBySearch failed: %v", err)
+	}
+
+	boundary, err := GetCityBoundary("R1", 0.01)
+	if err != nil || len(boundary.Polygons[0][0]) != 5 {
+		t.Fatalf("GetCityBoundary should simplify the ring: %+v %v", boundary, err)
+	}
+	encoded, _ := json.Marshal(boundary)
+	if string(encoded) != `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}` {
+		t.Fatalf("unexpected GeoJSON: %s", encoded)
+	}
+	var decoded Boundary
+	if err := json.Unmarshal(encoded, &decoded); err != nil || len(decoded.Polygons) != 1 {
+		t.Fatalf("boundary should round-trip: %+v %v", decoded, err)
+	}
+
+	if _, err := GetCityBoundary("N2", 0); err == nil {
+		t.Fatalf("point geometries should not be returned as boundaries")
+	}
+	if _, err := GetCityBoundary("Pfoo", 0); err == nil {
+		t.Fatalf("non OSM place IDs should be rejected")
+	}
+}
+
+func TestParseMultiPolygon(t *testing.T) {
+	raw := json.RawMessage(`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`)
+	boundary, err := parseBoundary(raw)
+	if err != nil || boundary.Type != GeometryMultiPolygon || len(boundary.Polygons) != 2 {
+		t.Fatalf("parseBoundary failed: %+v %v", boundary, err)
+	}
+	if b, err := parseBoundary(nil); b != nil || err != nil {
+		t.Fatalf("missing geojson should yield no boundary")
+	}
+}
@@ -0,0 +1,216 @@
+package posm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by LoadConfig, they override values from the config file
+const (
+	EnvLocationIQKey = "POSM_LOCATIONIQ_KEY"
+	EnvRegion        = "POSM_REGION"
+	EnvTimeout       = "POSM_TIMEOUT"
+	EnvUserAgent     = "POSM_USER_AGENT"
+	EnvCacheSize     = "POSM_CACHE_SIZE"
+	EnvRateLimit     = "POSM_RATE_LIMIT"
+	// EnvEndpointTimeouts holds per-endpoint timeouts such as "search=5s,autocomplete=1s"
+	EnvEndpointTimeouts = "POSM_ENDPOINT_TIMEOUTS"
+	EnvRoutingURL       = "POSM_ROUTING_URL"
+	EnvMatrixURL        = "POSM_MATRIX_URL"
+)
+
+// Duration is a time.Duration written as a string such as "5s" in config files
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config holds the settings used to initialize posm
+type Config struct {
+	LocationIQKey string `json:"locationiq_key"`
+	Region        string `json:"region,omitempty"`
+	// Timeout overrides DefaultTimeout when set, "0s" disables it
+	Timeout          *Duration           `json:"timeout,omitempty"`
+	EndpointTimeouts map[string]Duration `json:"endpoint_timeouts,omitempty"`
+	UserAgent        string              `json:"user_agent,omitempty"`
+	CacheSize        int                 `json:"cache_size,omitempty"`
+	RateLimit        float64             `json:"rate_limit,omitempty"`
+	// RoutingURL and MatrixURL send directions and matrix requests to OSRM servers instead of
+	// LocationIQ, geocoding always uses LocationIQ
+	RoutingURL string `json:"routing_url,omitempty"`
+	MatrixURL  string `json:"matrix_url,omitempty"`
+}
+
+// LoadConfig reads the JSON config file at path, if any, applies environment overrides,
+// validates the result and calls Init with it. There is no chain of providers to fall back on:
+// geocoding always uses LocationIQ, and RoutingURL and MatrixURL replace it for their endpoints.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	Init(cfg.LocationIQKey, cfg.Options()...)
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	if v, ok := os.LookupEnv(EnvLocationIQKey); ok {
+		c.LocationIQKey = v
+	}
+	if v, ok := os.LookupEnv(EnvRegion); ok {
+		c.Region = v
+	}
+	if v, ok := os.LookupEnv(EnvUserAgent); ok {
+		c.UserAgent = v
+	}
+	if v, ok := os.LookupEnv(EnvTimeout); ok {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvTimeout, v, err)
+		}
+		d := Duration(timeout)
+		c.Timeout = &d
+	}
+	if v, ok := os.LookupEnv(EnvEndpointTimeouts); ok {
+		timeouts, err := parseEndpointTimeouts(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvEndpointTimeouts, v, err)
+		}
+		c.EndpointTimeouts = timeouts
+	}
+	if v, ok := os.LookupEnv(EnvRoutingURL); ok {
+		c.RoutingURL = v
+	}
+	if v, ok := os.LookupEnv(EnvMatrixURL); ok {
+		c.MatrixURL = v
+	}
+	if v, ok := os.LookupEnv(EnvCacheSize); ok {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvCacheSize, v, err)
+		}
+		c.CacheSize = size
+	}
+	if v, ok := os.LookupEnv(EnvRateLimit); ok {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvRateLimit, v, err)
+		}
+		c.RateLimit = rate
+	}
+	return nil
+}
+
+// parseEndpointTimeouts reads a comma separated list of endpoint=duration pairs
+func parseEndpointTimeouts(v string) (map[string]Duration, error) {
+	timeouts := make(map[string]Duration)
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		endpoint, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected endpoint=duration, got %q", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		timeouts[strings.TrimSpace(endpoint)] = Duration(timeout)
+	}
+	return timeouts, nil
+}
+
+// Validate reports the first missing or invalid setting
+func (c *Config) Validate() error {
+	if strings.TrimSpace(c.LocationIQKey) == "" {
+		return fmt.Errorf("missing LocationIQ key: set %s or locationiq_key", EnvLocationIQKey)
+	}
+	if c.Region != "" && c.Region != RegionUS && c.Region != RegionEU {
+		return fmt.Errorf("invalid region %q: must be %q or %q", c.Region, RegionUS, RegionEU)
+	}
+	if c.Timeout != nil && *c.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %s: must not be negative", time.Duration(*c.Timeout))
+	}
+	for endpoint, timeout := range c.EndpointTimeouts {
+		if !isKnownEndpoint(endpoint) {
+			return fmt.Errorf("invalid endpoint_timeouts key %q", endpoint)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("invalid %s timeout %s: must not be negative", endpoint, time.Duration(timeout))
+		}
+	}
+	if c.CacheSize < 0 {
+		return fmt.Errorf("invalid cache size %d: must not be negative", c.CacheSize)
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("invalid rate limit %v: must not be negative", c.RateLimit)
+	}
+	for name, value := range map[string]string{"routing_url": c.RoutingURL, "matrix_url": c.MatrixURL} {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s %q: must be an http or https URL", name, value)
+		}
+	}
+	return nil
+}
+
+// Options converts the config into Init options
+func (c *Config) Options() []Option {
+	opts := []Option{
+		WithCacheSize(c.CacheSize),
+		WithRateLimit(c.RateLimit),
+	}
+	if c.Region != "" {
+		opts = append(opts, WithRegion(c.Region))
+	}
+	if c.Timeout != nil {
+		opts = append(opts, WithTimeout(time.Duration(*c.Timeout)))
+	}
+	for endpoint, timeout := range c.EndpointTimeouts {
+		opts = append(opts, WithEndpointTimeout(endpoint, time.Duration(timeout)))
+	}
+	if c.UserAgent != "" {
+		opts = append(opts, WithUserAgent(c.UserAgent))
+	}
+	if c.RoutingURL != "" {
+		opts = append(opts, WithRoutingURL(c.RoutingURL))
+	}
+	if c.MatrixURL != "" {
+		opts = append(opts, WithMatrixURL(c.MatrixURL))
+	}
+	return opts
+}
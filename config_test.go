@@ -0,0 +1,115 @@
+package posm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posm.json")
+	content := `{"locationiq_key":"file-key","region":"eu","timeout":"3s","endpoint_timeouts":{"autocomplete":"1s"},"cache_size":10}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvLocationIQKey, "env-key")
+	t.Setenv(EnvRateLimit, "2")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	defer Init("")
+	if cfg.LocationIQKey != "env-key" || cfg.RateLimit != 2 || cfg.CacheSize != 10 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if locationIQAccessToken != "env-key" {
+		t.Fatalf("LoadConfig did not initialize posm")
+	}
+	if !strings.HasPrefix(searchClient.BaseURL, "https://eu1.locationiq.com/") {
+		t.Fatalf("region not applied: %s", searchClient.BaseURL)
+	}
+	if searchClient.HTTPClient.Timeout != 3*time.Second || autoCompleteClient.HTTPClient.Timeout != time.Second {
+		t.Fatalf("timeouts not applied")
+	}
+	if cache == nil || limiter == nil {
+		t.Fatalf("cache and rate limiter should be configured")
+	}
+}
+
+func TestLoadConfigRoutingFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posm.json")
+	content := `{"locationiq_key":"file-key","routing_url":"http://osrm.internal:5000/route/v1"}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvMatrixURL, "http://osrm.internal:5000/table/v1")
+	t.Setenv(EnvEndpointTimeouts, "reverse=2s, directions=30s")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	defer Init("")
+	if cfg.EndpointTimeouts[EndpointReverse] != Duration(2*time.Second) {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if directionsClient.BaseURL != "http://osrm.internal:5000/route/v1" || !directionsClient.keyless {
+		t.Fatalf("routing URL not applied: %s", directionsClient.BaseURL)
+	}
+	if matrixClient.BaseURL != "http://osrm.internal:5000/table/v1" || !matrixClient.keyless {
+		t.Fatalf("matrix URL not applied: %s", matrixClient.BaseURL)
+	}
+	if reverseClient.HTTPClient.Timeout != 2*time.Second || directionsClient.HTTPClient.Timeout != 30*time.Second {
+		t.Fatalf("endpoint timeouts not applied")
+	}
+
+	t.Setenv(EnvEndpointTimeouts, "reverse")
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), EnvEndpointTimeouts) {
+		t.Fatalf("invalid endpoint timeouts should be reported, got %v", err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv(EnvLocationIQKey, "")
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), EnvLocationIQKey) {
+		t.Fatalf("missing key should be reported, got %v", err)
+	}
+
+	t.Setenv(EnvLocationIQKey, "key")
+	t.Setenv(EnvTimeout, "soon")
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), EnvTimeout) {
+		t.Fatalf("invalid timeout should be reported, got %v", err)
+	}
+
+	// an explicit zero disables the timeout instead of keeping the default
+	t.Setenv(EnvTimeout, "0s")
+	cfg, err := LoadConfig("")
+	if err != nil || cfg.Timeout == nil || searchClient.HTTPClient.Timeout != 0 {
+		t.Fatalf("a zero timeout should disable it: %+v %v", cfg, err)
+	}
+	Init("")
+	if searchClient.HTTPClient.Timeout != DefaultTimeout {
+		t.Fatalf("an unset timeout should keep the default, got %v", searchClient.HTTPClient.Timeout)
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatalf("missing config file should be reported")
+	}
+
+	invalid := []Config{
+		{LocationIQKey: "k", Region: "asia"},
+		{LocationIQKey: "k", CacheSize: -1},
+		{LocationIQKey: "k", RateLimit: -1},
+		{LocationIQKey: "k", EndpointTimeouts: map[string]Duration{"geocode": Duration(time.Second)}},
+		{LocationIQKey: "k", RoutingURL: "osrm.internal:5000"},
+		{LocationIQKey: "k", MatrixURL: "ftp://osrm.internal/table/v1"},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("Validate should reject %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package posm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PlaceDetails holds the extra tags and name variants OpenStreetMap has for a place
+type PlaceDetails struct {
+	WikidataID   string `json:"wikidata_id,omitempty"`
+	Wikipedia    string `json:"wikipedia,omitempty"`
+	Population   int64  `json:"population,omitempty"`
+	OfficialName string `json:"official_name,omitempty"`
+	// Names maps a language code to the name in that language, "" is the default name
+	Names map[string]string `json:"names,omitempty"`
+	// ExtraTags are all extra OSM tags as returned upstream
+	ExtraTags map[string]string `json:"extra_tags,omitempty"`
+}
+
+// Name returns the name in the first available language of the chain, such as "de-CH", "de",
+// falling back to the default name and then the official name
+func (d *PlaceDetails) Name(languages ...string) string {
+	if d == nil {
+		return ""
+	}
+	for _, language := range languages {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if name := d.Names[language]; name != "" {
+			return name
+		}
+		if base, _, found := strings.Cut(language, "-"); found {
+			if name := d.Names[base]; name != "" {
+				return name
+			}
+		}
+	}
+	if name := d.Names[""]; name != "" {
+		return name
+	}
+	return d.OfficialName
+}
+
+// getDetails parses extratags and namedetails, returning nil when neither was sent
+func (lr *locationIQResponse) getDetails() *PlaceDetails {
+	if lr == nil || (len(lr.ExtraTags) == 0 && len(lr.NameDetails) == 0) {
+		return nil
+	}
+	details := &PlaceDetails{
+		WikidataID: lr.ExtraTags["wikidata"],
+		Wikipedia:  lr.ExtraTags["wikipedia"],
+		Population: parsePopulation(lr.ExtraTags["population"]),
+		Names:      make(map[string]string),
+		ExtraTags:  lr.ExtraTags,
+	}
+	for key, value := range lr.NameDetails {
+		switch {
+		case key == "name":
+			details.Names[""] = value
+		case key == "official_name":
+			details.OfficialName = value
+		case strings.HasPrefix(key, "name:"):
+			details.Names[strings.ToLower(strings.TrimPrefix(key, "name:"))] = value
+		}
+	}
+	if details.OfficialName == "" {
+		details.OfficialName = lr.ExtraTags["official_name"]
+	}
+	return details
+}
+
+// parsePopulation accepts values like "873965" and "873,965", returning 0 for anything else
+func parsePopulation(value string) int64 {
+	value = strings.NewReplacer(",", "", " ", "", "_", "").Replace(value)
+	population, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || population < 0 {
+		return 0
+	}
+	return population
+}
@@ -0,0 +1,49 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlaceDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("extratags") != "1" || q.Get("namedetails") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[ {"osm_type":"relation","osm_id":"62422","display_name":"Berlin","lat":"52.5","lon":"13.4",
+			"address":{"city":"Berlin","state":"Berlin","country_code":"de"},
+			"extratags":{"wikidata":"Q64","wikipedia":"de:Berlin","population":"3,769,495"},
+			"namedetails":{"name":"Berlin","name:ru":"Берлин","name:zh":"柏林","official_name":"Land Berlin"}} ]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	city, err := GetCityBySearch("berlin")
+	if err != nil || city.Details == nil {
+		t.Fatalf("GetCityBySearch should include details: %+v %v", city, err)
+	}
+	details := city.Details
+	if details.WikidataID != "Q64" || details.Wikipedia != "de:Berlin" || details.Population != 3769495 || details.OfficialName != "Land Berlin" {
+		t.Fatalf("unexpected details: %+v", details)
+	}
+	if got := details.Name("fr", "ru-RU", "zh"); got != "Берлин" {
+		t.Fatalf("Name should follow the language chain, got %q", got)
+	}
+	if got := details.Name("fr"); got != "Berlin" {
+		t.Fatalf("Name should fall back to the default name, got %q", got)
+	}
+
+	point, err := GetPointByLookup("R62422")
+	if err != nil || point.Details == nil || point.Details.Names["zh"] != "柏林" {
+		t.Fatalf("GetPointByLookup should include details: %+v %v", point, err)
+	}
+
+	var nilDetails *PlaceDetails
+	if nilDetails.Name("en") != "" || parsePopulation("about 5") != 0 {
+		t.Fatalf("nil details and bad populations should be empty")
+	}
+}
@@ -0,0 +1,114 @@
+package posm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultAddressTemplate keeps the historical "house street, city, state, postcode" order
+const defaultAddressTemplate = "{house_number} {street}\n{city}\n{state}\n{postcode}"
+
+// addressTemplates maps lower-case country codes to a template with one address line per row.
+// Placeholders name Address fields, {state_code} is the state formatted with the StateNames option,
+// or the short state code when the state name is missing.
+var addressTemplates = map[string]string{
+	"us": "{house_number} {street}\n{city}, {state_code} {postcode}",
+	"ca": "{house_number} {street}\n{city} {state_code} {postcode}",
+	"au": "{house_number} {street}\n{city} {state_code} {postcode}",
+	"gb": "{house_name}\n{house_number} {street}\n{city}\n{postcode}",
+	"ie": "{house_number} {street}\n{city}\n{postcode}",
+	"fr": "{house_number} {street}\n{postcode} {city}",
+	"de": "{street} {house_number}\n{postcode} {city}",
+	"at": "{street} {house_number}\n{postcode} {city}",
+	"ch": "{street} {house_number}\n{postcode} {city}",
+	"nl": "{street} {house_number}\n{postcode} {city}",
+	"be": "{street} {house_number}\n{postcode} {city}",
+	"dk": "{street} {house_number}\n{postcode} {city}",
+	"no": "{street} {house_number}\n{postcode} {city}",
+	"se": "{street} {house_number}\n{postcode} {city}",
+	"fi": "{street} {house_number}\n{postcode} {city}",
+	"pl": "{street} {house_number}\n{postcode} {city}",
+	"cz": "{street} {house_number}\n{postcode} {city}",
+	"es": "{street}, {house_number}\n{postcode} {city}",
+	"it": "{street}, {house_number}\n{postcode} {city} {state_code}",
+	"br": "{street}, {house_number}\n{neighbourhood}\n{city} - {state_code}\n{postcode}",
+	"jp": "{postcode}\n{state} {city}\n{street} {house_number}",
+}
+
+var (
+	addressPlaceholder = regexp.MustCompile(`\{[a-z_]+\}`)
+	// emptySeparators matches the separators left behind by empty placeholders, e.g. "a, , b"
+	emptySeparators = regexp.MustCompile(`\s*([,-])(\s*[,-])+\s*`)
+)
+
+// Format returns the address on one line in the order used by its country, without the country
+func (a *Address) Format() string {
+	return strings.Join(a.formatLines(), ", ")
+}
+
+// FormatLines returns the address as postal lines in the order used by its country,
+// followed by the country name
+func (a *Address) FormatLines() []string {
+	lines := a.formatLines()
+	if a != nil && a.Country != "" {
+		lines = append(lines, a.Country)
+	}
+	return lines
+}
+
+func (a *Address) formatLines() []string {
+	if a == nil {
+		return nil
+	}
+	template, ok := addressTemplates[strings.ToLower(a.CountryCode)]
+	if !ok {
+		template = defaultAddressTemplate
+	}
+	lines := make([]string, 0, 4)
+	for _, row := range strings.Split(template, "\n") {
+		line := addressPlaceholder.ReplaceAllStringFunc(row, a.placeholder)
+		if line = cleanAddressLine(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (a *Address) placeholder(name string) string {
+	switch strings.Trim(name, "{}") {
+	case "house_name":
+		return a.HouseName
+	case "house_number":
+		// a number without a street locates nothing
+		if a.Street == "" {
+			return ""
+		}
+		return a.HouseNumber
+	case "street":
+		return a.Street
+	case "neighbourhood":
+		return firstNonEmpty(a.Neighbourhood, a.Suburb)
+	case "city":
+		return a.City
+	case "state":
+		return formatState(a.CountryCode, a.State)
+	case "state_code":
+		// the state is only shortened when the StateNames option asks for it, like the city and street addresses
+		_, code, _ := strings.Cut(a.StateCode, "-")
+		if stateNames == StateNamesAbbreviated && code != "" {
+			return code
+		}
+		return firstNonEmpty(formatState(a.CountryCode, a.State), code)
+	case "postcode":
+		return a.Postcode
+	}
+	return ""
+}
+
+// cleanAddressLine drops the separators and spaces around empty placeholders
+func cleanAddressLine(line string) string {
+	line = strings.Join(strings.Fields(line), " ")
+	line = emptySeparators.ReplaceAllString(line, "$1 ")
+	line = strings.ReplaceAll(line, " ,", ",")
+	return strings.Trim(line, " ,-")
+}
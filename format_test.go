@@ -0,0 +1,81 @@
+package posm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddressFormat(t *testing.T) {
+	cases := []struct {
+		address Address
+		line    string
+		lines   []string
+	}{
+		{
+			Address{HouseNumber: "10", Street: "Market St", City: "San Francisco", State: "California", StateCode: "US-CA", Postcode: "94105", Country: "United States", CountryCode: "us"},
+			"10 Market St, San Francisco, California 94105",
+			[]string{"10 Market St", "San Francisco, California 94105", "United States"},
+		},
+		{
+			Address{HouseNumber: "1", Street: "Pariser Platz", City: "Berlin", State: "Berlin", Postcode: "10117", Country: "Deutschland", CountryCode: "DE"},
+			"Pariser Platz 1, 10117 Berlin",
+			[]string{"Pariser Platz 1", "10117 Berlin", "Deutschland"},
+		},
+		{
+			Address{HouseName: "Bush House", HouseNumber: "30", Street: "Aldwych", City: "London", Postcode: "WC2B 4BG", CountryCode: "gb"},
+			"Bush House, 30 Aldwych, London, WC2B 4BG",
+			[]string{"Bush House", "30 Aldwych", "London", "WC2B 4BG"},
+		},
+		{
+			Address{HouseNumber: "8-1", Street: "Nishi-Shinjuku 2-chome", City: "Shinjuku", State: "Tokyo", Postcode: "160-0023", CountryCode: "jp"},
+			"160-0023, Tokyo Shinjuku, Nishi-Shinjuku 2-chome 8-1",
+			[]string{"160-0023", "Tokyo Shinjuku", "Nishi-Shinjuku 2-chome 8-1"},
+		},
+		{
+			Address{Street: "Avenida Paulista", HouseNumber: "1578", City: "São Paulo", StateCode: "BR-SP", CountryCode: "br"},
+			"Avenida Paulista, 1578, São Paulo - SP",
+			[]string{"Avenida Paulista, 1578", "São Paulo - SP"},
+		},
+		{
+			// empty placeholders leave no dangling separators
+			Address{City: "Springfield", CountryCode: "us"},
+			"Springfield",
+			[]string{"Springfield"},
+		},
+		{
+			// unknown countries keep the historical order
+			Address{HouseNumber: "5", Street: "Main Rd", City: "Suva", State: "Central", Postcode: "0000", CountryCode: "fj"},
+			"5 Main Rd, Suva, Central, 0000",
+			[]string{"5 Main Rd", "Suva", "Central", "0000"},
+		},
+	}
+	for _, c := range cases {
+		if got := c.address.Format(); got != c.line {
+			t.Fatalf("Format() = %q, want %q", got, c.line)
+		}
+		if got := c.address.FormatLines(); !reflect.DeepEqual(got, c.lines) {
+			t.Fatalf("FormatLines() = %q, want %q", got, c.lines)
+		}
+	}
+	var nilAddress *Address
+	if nilAddress.Format() != "" || nilAddress.FormatLines() != nil {
+		t.Fatalf("nil addresses should format as empty")
+	}
+
+	defer func() { stateNames = StateNamesAsReturned }()
+	stateNames = StateNamesAbbreviated
+	for address, want := range map[Address]string{
+		{Street: "Market St", City: "San Francisco", State: "California", StateCode: "US-CA", CountryCode: "us"}: "Market St, San Francisco, CA",
+		{Street: "Bay St", City: "Toronto", State: "Ontario", CountryCode: "ca"}:                                 "Bay St, Toronto ON",
+	} {
+		if got := address.Format(); got != want {
+			t.Fatalf("abbreviated Format() = %q, want %q", got, want)
+		}
+	}
+	stateNames = StateNamesAsReturned
+
+	resp := &locationIQResponse{Address: &address{Road: "Pariser Platz", HouseNumber: "1", City: "Berlin", Postcode: "10117", CountryCode: "de"}}
+	if got := resp.getPointAddress(); got != "Pariser Platz 1, 10117 Berlin" {
+		t.Fatalf("getPointAddress() = %q", got)
+	}
+}
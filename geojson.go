@@ -0,0 +1,282 @@
+package posm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GeoJSON object types used for results
+const (
+	geoJSONTypeFeature           = "Feature"
+	geoJSONTypeFeatureCollection = "FeatureCollection"
+	geometryPoint                = "Point"
+)
+
+// geoJSONFeature is an RFC 7946 feature, bbox is [west, south, east, north]
+type geoJSONFeature struct {
+	Type       string                     `json:"type"`
+	BBox       []float64                  `json:"bbox,omitempty"`
+	Geometry   *geoJSONGeometry           `json:"geometry"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// MarshalGeoJSON encodes the city as a Point feature, the boundary is kept in the properties
+func (c *OsmCity) MarshalGeoJSON() ([]byte, error) {
+	return marshalGeoJSON(c.toFeature)
+}
+
+// UnmarshalGeoJSON decodes a feature written by MarshalGeoJSON
+func (c *OsmCity) UnmarshalGeoJSON(data []byte) error {
+	return unmarshalGeoJSON(data, c.fromFeature)
+}
+
+// MarshalGeoJSON encodes the point as a Point feature
+func (p *OsmPoint) MarshalGeoJSON() ([]byte, error) {
+	return marshalGeoJSON(p.toFeature)
+}
+
+// UnmarshalGeoJSON decodes a feature written by MarshalGeoJSON
+func (p *OsmPoint) UnmarshalGeoJSON(data []byte) error {
+	return unmarshalGeoJSON(data, p.fromFeature)
+}
+
+// MarshalGeoJSON encodes the street as a Point feature
+func (s *OsmStreet) MarshalGeoJSON() ([]byte, error) {
+	return marshalGeoJSON(s.toFeature)
+}
+
+// UnmarshalGeoJSON decodes a feature written by MarshalGeoJSON
+func (s *OsmStreet) UnmarshalGeoJSON(data []byte) error {
+	return unmarshalGeoJSON(data, s.fromFeature)
+}
+
+// MarshalCitiesGeoJSON encodes the cities as a FeatureCollection
+func MarshalCitiesGeoJSON(cities []*OsmCity) ([]byte, error) {
+	return marshalFeatureCollection(len(cities), func(i int) (*geoJSONFeature, error) {
+		return cities[i].toFeature()
+	})
+}
+
+// UnmarshalCitiesGeoJSON decodes a FeatureCollection written by MarshalCitiesGeoJSON
+func UnmarshalCitiesGeoJSON(data []byte) ([]*OsmCity, error) {
+	features, err := unmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	cities := make([]*OsmCity, len(features))
+	for i := range features {
+		cities[i] = &OsmCity{}
+		if err := cities[i].fromFeature(&features[i]); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	return cities, nil
+}
+
+// MarshalPointsGeoJSON encodes the points as a FeatureCollection
+func MarshalPointsGeoJSON(points []*OsmPoint) ([]byte, error) {
+	return marshalFeatureCollection(len(points), func(i int) (*geoJSONFeature, error) {
+		return points[i].toFeature()
+	})
+}
+
+// UnmarshalPointsGeoJSON decodes a FeatureCollection written by MarshalPointsGeoJSON
+func UnmarshalPointsGeoJSON(data []byte) ([]*OsmPoint, error) {
+	features, err := unmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]*OsmPoint, len(features))
+	for i := range features {
+		points[i] = &OsmPoint{}
+		if err := points[i].fromFeature(&features[i]); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	return points, nil
+}
+
+// MarshalStreetsGeoJSON encodes the streets as a FeatureCollection
+func MarshalStreetsGeoJSON(streets []*OsmStreet) ([]byte, error) {
+	return marshalFeatureCollection(len(streets), func(i int) (*geoJSONFeature, error) {
+		return streets[i].toFeature()
+	})
+}
+
+// UnmarshalStreetsGeoJSON decodes a FeatureCollection written by MarshalStreetsGeoJSON
+func UnmarshalStreetsGeoJSON(data []byte) ([]*OsmStreet, error) {
+	features, err := unmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	streets := make([]*OsmStreet, len(features))
+	for i := range features {
+		streets[i] = &OsmStreet{}
+		if err := streets[i].fromFeature(&features[i]); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	return streets, nil
+}
+
+func (c *OsmCity) toFeature() (*geoJSONFeature, error) {
+	if c == nil {
+		return nil, fmt.Errorf("nil city")
+	}
+	return newFeature(c, c.Coordinate(), c.BBox)
+}
+
+func (c *OsmCity) fromFeature(feature *geoJSONFeature) error {
+	coordinate, bbox, err := readFeature(feature, c)
+	if err != nil {
+		return err
+	}
+	c.Lat, c.Lng, c.BBox = coordinate.Lat, coordinate.Lng, bbox
+	return nil
+}
+
+func (p *OsmPoint) toFeature() (*geoJSONFeature, error) {
+	if p == nil {
+		return nil, fmt.Errorf("nil point")
+	}
+	return newFeature(p, p.Coordinate(), p.BBox)
+}
+
+func (p *OsmPoint) fromFeature(feature *geoJSONFeature) error {
+	coordinate, bbox, err := readFeature(feature, p)
+	if err != nil {
+		return err
+	}
+	p.Lat, p.Lng, p.BBox = coordinate.Lat, coordinate.Lng, bbox
+	return nil
+}
+
+func (s *OsmStreet) toFeature() (*geoJSONFeature, error) {
+	if s == nil {
+		return nil, fmt.Errorf("nil street")
+	}
+	return newFeature(s, s.Coordinate(), s.BBox)
+}
+
+func (s *OsmStreet) fromFeature(feature *geoJSONFeature) error {
+	coordinate, bbox, err := readFeature(feature, s)
+	if err != nil {
+		return err
+	}
+	s.Lat, s.Lng, s.BBox = coordinate.Lat, coordinate.Lng, bbox
+	return nil
+}
+
+// newFeature uses the JSON encoding of result as properties, moving the location and bbox
+// to the geometry and bbox members
+func newFeature(result any, location Coordinate, bbox *BBox) (*geoJSONFeature, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return nil, err
+	}
+	delete(properties, "lat")
+	delete(properties, "lng")
+	delete(properties, "bbox")
+	coordinates, err := json.Marshal([2]float64{location.Lng, location.Lat})
+	if err != nil {
+		return nil, err
+	}
+	feature := &geoJSONFeature{
+		Type:       geoJSONTypeFeature,
+		Geometry:   &geoJSONGeometry{Type: geometryPoint, Coordinates: coordinates},
+		Properties: properties,
+	}
+	if bbox != nil {
+		feature.BBox = []float64{bbox.MinLng, bbox.MinLat, bbox.MaxLng, bbox.MaxLat}
+	}
+	return feature, nil
+}
+
+// readFeature decodes the properties into result and returns the location and bbox
+func readFeature(feature *geoJSONFeature, result any) (Coordinate, *BBox, error) {
+	var location Coordinate
+	if feature.Type != geoJSONTypeFeature {
+		return location, nil, fmt.Errorf("unexpected geojson type %q", feature.Type)
+	}
+	if feature.Geometry == nil || feature.Geometry.Type != geometryPoint {
+		return location, nil, fmt.Errorf("feature geometry is not a point")
+	}
+	var position []float64
+	if err := json.Unmarshal(feature.Geometry.Coordinates, &position); err != nil {
+		return location, nil, fmt.Errorf("failed to decode point: %w", err)
+	}
+	if len(position) < 2 {
+		return location, nil, fmt.Errorf("point needs a longitude and a latitude")
+	}
+	location = Coordinate{Lat: position[1], Lng: position[0]}
+	if err := location.validate(); err != nil {
+		return location, nil, err
+	}
+	var bbox *BBox
+	if len(feature.BBox) != 0 {
+		if len(feature.BBox) != 4 {
+			return location, nil, fmt.Errorf("unsupported bbox with %d values", len(feature.BBox))
+		}
+		bbox = &BBox{MinLng: feature.BBox[0], MinLat: feature.BBox[1], MaxLng: feature.BBox[2], MaxLat: feature.BBox[3]}
+	}
+	properties := feature.Properties
+	if properties == nil {
+		properties = map[string]json.RawMessage{}
+	}
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return location, nil, err
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return location, nil, fmt.Errorf("failed to decode properties: %w", err)
+	}
+	return location, bbox, nil
+}
+
+func marshalGeoJSON(toFeature func() (*geoJSONFeature, error)) ([]byte, error) {
+	feature, err := toFeature()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(feature)
+}
+
+func unmarshalGeoJSON(data []byte, fromFeature func(*geoJSONFeature) error) error {
+	var feature geoJSONFeature
+	if err := json.Unmarshal(data, &feature); err != nil {
+		return fmt.Errorf("failed to decode geojson: %w", err)
+	}
+	return fromFeature(&feature)
+}
+
+func marshalFeatureCollection(count int, feature func(int) (*geoJSONFeature, error)) ([]byte, error) {
+	collection := geoJSONFeatureCollection{Type: geoJSONTypeFeatureCollection, Features: make([]geoJSONFeature, count)}
+	for i := 0; i < count; i++ {
+		f, err := feature(i)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		collection.Features[i] = *f
+	}
+	return json.Marshal(collection)
+}
+
+func unmarshalFeatureCollection(data []byte) ([]geoJSONFeature, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("failed to decode geojson: %w", err)
+	}
+	if collection.Type != geoJSONTypeFeatureCollection {
+		return nil, fmt.Errorf("unexpected geojson type %q", collection.Type)
+	}
+	return collection.Features, nil
+}
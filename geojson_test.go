@@ -0,0 +1,86 @@
+package posm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCityGeoJSON(t *testing.T) {
+	city := &OsmCity{
+		PlaceID:     "R111968",
+		Lat:         37.77,
+		Lng:         -122.42,
+		DisplayName: "San Francisco, California, United States",
+		Address:     "San Francisco, California",
+		BBox:        &BBox{MinLat: 37.6, MinLng: -122.5, MaxLat: 37.8, MaxLng: -122.3},
+		Boundary:    &Boundary{Type: GeometryPolygon, Polygons: [][][][2]float64{{{{-122.5, 37.6}, {-122.3, 37.6}, {-122.3, 37.8}, {-122.5, 37.6}}}}},
+	}
+	data, err := city.MarshalGeoJSON()
+	if err != nil {
+		t.Fatalf("MarshalGeoJSON error: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("invalid JSON %s", data)
+	}
+	geometry := raw["geometry"].(map[string]any)
+	if raw["type"] != "Feature" || geometry["type"] != "Point" || geometry["coordinates"].([]any)[0] != -122.42 {
+		t.Fatalf("unexpected feature %s", data)
+	}
+	if bbox := raw["bbox"].([]any); bbox[0] != -122.5 || bbox[1] != 37.6 {
+		t.Fatalf("bbox should be [west, south, east, north], got %v", bbox)
+	}
+	properties := raw["properties"].(map[string]any)
+	if properties["place_id"] != "R111968" || properties["boundary"] == nil || properties["lat"] != nil {
+		t.Fatalf("unexpected properties %v", properties)
+	}
+
+	var decoded OsmCity
+	if err := decoded.UnmarshalGeoJSON(data); err != nil {
+		t.Fatalf("UnmarshalGeoJSON error: %v", err)
+	}
+	if decoded.PlaceID != city.PlaceID || decoded.Lat != city.Lat || decoded.Lng != city.Lng || *decoded.BBox != *city.BBox || decoded.Boundary == nil || decoded.Address != city.Address {
+		t.Fatalf("unexpected round trip %+v", decoded)
+	}
+
+	if err := decoded.UnmarshalGeoJSON([]byte(`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},"properties":{}}`)); err == nil {
+		t.Fatalf("non-point geometries should be rejected")
+	}
+}
+
+func TestCollectionGeoJSON(t *testing.T) {
+	points := []*OsmPoint{
+		{PlaceID: "N1", Lat: 1, Lng: 2, Name: "Cafe"},
+		{PlaceID: "N2", Lat: 3, Lng: 4, Address: "1 Main St"},
+	}
+	data, err := MarshalPointsGeoJSON(points)
+	if err != nil {
+		t.Fatalf("MarshalPointsGeoJSON error: %v", err)
+	}
+	if !strings.Contains(string(data), `"type":"FeatureCollection"`) || !strings.Contains(string(data), `"coordinates":[4,3]`) {
+		t.Fatalf("unexpected collection %s", data)
+	}
+	decoded, err := UnmarshalPointsGeoJSON(data)
+	if err != nil || len(decoded) != 2 || decoded[0].Name != "Cafe" || decoded[1].Lat != 3 || decoded[1].Lng != 4 || decoded[1].BBox != nil {
+		t.Fatalf("unexpected points %+v, err %v", decoded, err)
+	}
+
+	streets, err := UnmarshalStreetsGeoJSON([]byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[-0.12,51.5,11]},"properties":{"place_id":"W9","address":"Strand, London"}}]}`))
+	if err != nil || len(streets) != 1 || streets[0].PlaceID != "W9" || streets[0].Lat != 51.5 || streets[0].Lng != -0.12 {
+		t.Fatalf("unexpected streets %+v, err %v", streets, err)
+	}
+	if _, err := MarshalPointsGeoJSON([]*OsmPoint{points[0], nil}); err == nil || !strings.Contains(err.Error(), "feature 1") {
+		t.Fatalf("nil points should be reported, got %v", err)
+	}
+	if _, err := MarshalCitiesGeoJSON([]*OsmCity{nil}); err == nil {
+		t.Fatalf("nil cities should be reported")
+	}
+	if _, err := (*OsmStreet)(nil).MarshalGeoJSON(); err == nil {
+		t.Fatalf("nil streets should be reported")
+	}
+	if _, err := UnmarshalCitiesGeoJSON([]byte(`{"type":"Feature"}`)); err == nil {
+		t.Fatalf("a single feature is not a collection")
+	}
+}
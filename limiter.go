@@ -0,0 +1,123 @@
+package posm
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces upstream requests evenly so they never exceed a fixed rate
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var limiter *rateLimiter
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// wait blocks until the next request slot or until ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// responseCache keeps the bodies of recent successful responses, evicting the least recently used
+type responseCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	body []byte
+}
+
+var cache *responseCache
+
+func newResponseCache(size int) *responseCache {
+	if size <= 0 {
+		return nil
+	}
+	return &responseCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *responseCache) get(key string) (*http.Response, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(elem.Value.(*cacheEntry).body)),
+	}, true
+}
+
+// store caches a 200 response and returns an equivalent response with an unread body
+func (c *responseCache) store(key string, resp *http.Response) (*http.Response, error) {
+	if c == nil || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*cacheEntry).body = body
+		c.order.MoveToFront(elem)
+		return resp, nil
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, body: body})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+	return resp, nil
+}
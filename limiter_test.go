@@ -0,0 +1,64 @@
+package posm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprint(w, `[ {"place_id":"1","display_name":"City","lat":"1","lon":"2","address":{"city":"SF","state":"CA"}} ]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+	cache = newResponseCache(1)
+	defer func() { cache = nil }()
+
+	for i := 0; i < 2; i++ {
+		if _, err := GetCityBySearch("sf"); err != nil {
+			t.Fatalf("GetCityBySearch failed: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("second identical request should be served from cache, got %d calls", calls)
+	}
+	if _, err := GetCityBySearch("oakland"); err != nil {
+		t.Fatalf("GetCityBySearch failed: %v", err)
+	}
+	if _, err := GetCityBySearch("sf"); err != nil {
+		t.Fatalf("GetCityBySearch failed: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("least recently used entry should be evicted, got %d calls", calls)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(0) != nil {
+		t.Fatalf("zero rate should disable the limiter")
+	}
+	l := newRateLimiter(50)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("limiter did not space requests: %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l = newRateLimiter(0.1)
+	_ = l.wait(ctx)
+	if err := l.wait(ctx); err == nil {
+		t.Fatalf("wait should stop when the context is done")
+	}
+}
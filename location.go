@@ -1,18 +1,28 @@
 package posm
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 )
 
 type locationIQResponse struct {
-	PlaceID     string   `json:"place_id"`
-	OsmID       string   `json:"osm_id"`
-	OsmType     string   `json:"osm_type"`
-	DisplayName string   `json:"display_name"`
-	Lat         string   `json:"lat"`
-	Lng         string   `json:"lon"`
-	Address     *address `json:"address"`
+	PlaceID     string            `json:"place_id"`
+	OsmID       string            `json:"osm_id"`
+	OsmType     string            `json:"osm_type"`
+	DisplayName string            `json:"display_name"`
+	Lat         string            `json:"lat"`
+	Lng         string            `json:"lon"`
+	Address     *address          `json:"address"`
+	Name        string            `json:"name"`
+	Class       string            `json:"class"`
+	Type        string            `json:"type"`
+	Distance    float64           `json:"distance"`
+	BoundingBox []string          `json:"boundingbox"`
+	ExtraTags   map[string]string `json:"extratags"`
+	NameDetails map[string]string `json:"namedetails"`
+	// GeoJSON is only present when polygon_geojson=1 is requested
+	GeoJSON json.RawMessage `json:"geojson"`
 }
 
 func (lr *locationIQResponse) getPointAddress() string {
@@ -22,7 +32,7 @@ func (lr *locationIQResponse) getPointAddress() string {
 	if lr.Address == nil {
 		return lr.DisplayName
 	}
-	return lr.Address.getAddress()
+	return lr.Address.export().Format()
 }
 
 func (lr *locationIQResponse) getCityAddress() string {
@@ -37,7 +47,7 @@ func (lr *locationIQResponse) getCityAddress() string {
 	if city == "" {
 		city = address.County
 	}
-	return fmt.Sprintf("%s, %s", city, address.State)
+	return fmt.Sprintf("%s, %s", city, address.displayState())
 }
 
 func (lr *locationIQResponse) getStreetAddress() string {
@@ -48,7 +58,7 @@ func (lr *locationIQResponse) getStreetAddress() string {
 		return lr.DisplayName
 	}
 	address := lr.Address
-	return fmt.Sprintf("%s, %s, %s", address.getStreet(), address.getCity(), address.State)
+	return fmt.Sprintf("%s, %s, %s", address.getStreet(), address.getCity(), address.displayState())
 }
 
 func (lr *locationIQResponse) getStreetSearchText() string {
@@ -63,7 +73,7 @@ func (lr *locationIQResponse) getStreetSearchText() string {
 	if street == "" {
 		return ""
 	}
-	return fmt.Sprintf("%s, %s, %s, %s", street, address.getCity(), address.State, address.CountryCode)
+	return fmt.Sprintf("%s, %s, %s, %s", street, address.getCity(), address.displayState(), address.CountryCode)
 }
 
 func (lr *locationIQResponse) getCitySearchText() string {
@@ -78,7 +88,18 @@ func (lr *locationIQResponse) getCitySearchText() string {
 	if city == "" {
 		return ""
 	}
-	return fmt.Sprintf("%s, %s, %s", city, address.State, address.CountryCode)
+	return fmt.Sprintf("%s, %s, %s", city, address.displayState(), address.CountryCode)
+}
+
+// getCategory returns the OSM class and type, e.g. "amenity:cafe"
+func (lr *locationIQResponse) getCategory() string {
+	if lr == nil || lr.Class == "" {
+		return ""
+	}
+	if lr.Type == "" {
+		return lr.Class
+	}
+	return fmt.Sprintf("%s:%s", lr.Class, lr.Type)
 }
 
 func (lr *locationIQResponse) parseCoordinates() (float64, float64, error) {
@@ -123,3 +123,110 @@ func TestLocationIQResponseMethods(t *testing.T) {
 		t.Fatalf("getPlaceID (fallback) = %q", got)
 	}
 }
+
+func TestAddressExport(t *testing.T) {
+	var nilAddress *address
+	if nilAddress.export() != nil {
+		t.Fatalf("nil export should return nil")
+	}
+	a := &address{
+		HouseNumber: "10",
+		Pedestrian:  "Market Walk",
+		Suburb:      "SoMa",
+		Town:        "San Francisco",
+		County:      "San Francisco County",
+		State:       "California",
+		Postcode:    "94105",
+		Country:     "United States",
+		CountryCode: "us",
+	}
+	want := Address{
+		HouseNumber: "10",
+		Street:      "Market Walk",
+		Suburb:      "SoMa",
+		City:        "San Francisco",
+		County:      "San Francisco County",
+		State:       "California",
+		StateCode:   "US-CA",
+		Postcode:    "94105",
+		Country:     "United States",
+		CountryCode: "us",
+	}
+	if got := a.export(); got == nil || *got != want {
+		t.Fatalf("export() = %+v", got)
+	}
+
+	point, err := getOsmPointFromLocationIQResponse(&locationIQResponse{Lat: "37.79", Lng: "-122.4", Address: a})
+	if err != nil || point.StructuredAddress == nil || point.StructuredAddress.Postcode != "94105" || point.Address == "" {
+		t.Fatalf("unexpected point %+v, err %v", point, err)
+	}
+}
+
+func TestAddressFallbacks(t *testing.T) {
+	// Tokyo: no road, the ward is the city and the prefecture is a province
+	tokyo := &address{
+		HouseNumber:   "8-1",
+		Quarter:       "Nishi-Shinjuku 2-chome",
+		Neighbourhood: "Nishi-Shinjuku",
+		CityDistrict:  "Shinjuku",
+		Province:      "Tokyo",
+		StateCode:     "JP-13",
+		Postcode:      "160-0023",
+		Building:      "Tokyo Metropolitan Government Building",
+	}
+	if got := tokyo.getStreet(); got != "Nishi-Shinjuku 2-chome" {
+		t.Fatalf("getStreet() = %q", got)
+	}
+	if got := tokyo.getCity(); got != "Shinjuku" {
+		t.Fatalf("getCity() = %q", got)
+	}
+	// place IDs hash getAddress, which ignores the newer fallbacks so stored IDs stay valid
+	if got := (&address{Road: "Main St", City: "Springfield", Province: "Ontario", Region: "East"}).getAddress(); got != "Main St, Springfield" {
+		t.Fatalf("getAddress() should not use province or region, got %q", got)
+	}
+
+	// a neighbourhood without a house number is not a street, so the result stays a city
+	neighbourhood := &address{Neighbourhood: "Mission", City: "San Francisco"}
+	if neighbourhood.getStreet() != "" || !neighbourhood.isCity() {
+		t.Fatalf("neighbourhood results should still be cities")
+	}
+
+	// the street and city fallbacks do not change which results are cities
+	for _, a := range []*address{
+		{HouseNumber: "8-1", Quarter: "Nishi-Shinjuku 2-chome", City: "Tokyo"},
+		{HouseNumber: "12", Neighbourhood: "Mission", City: "San Francisco"},
+	} {
+		if !a.isCity() {
+			t.Fatalf("isCity() should be true for %+v", a)
+		}
+	}
+	for _, a := range []*address{
+		{Borough: "Camden"},
+		{CityDistrict: "Shinjuku", Province: "Tokyo"},
+		{Municipality: "Oulu"},
+	} {
+		if a.isCity() {
+			t.Fatalf("isCity() should be false for %+v", a)
+		}
+	}
+	exported := tokyo.export()
+	if exported.State != "Tokyo" || exported.StateCode != "JP-13" || exported.HouseName != "Tokyo Metropolitan Government Building" || exported.CityDistrict != "Shinjuku" {
+		t.Fatalf("export() = %+v", exported)
+	}
+
+	// London: the borough stands in for a missing city
+	london := &address{Road: "Camden High Street", Borough: "London Borough of Camden", Region: "England", Shop: "Camden Lock Books"}
+	if got := london.getCity(); got != "London Borough of Camden" {
+		t.Fatalf("getCity() = %q", got)
+	}
+	if got := london.getState(); got != "England" {
+		t.Fatalf("getState() = %q", got)
+	}
+	if exported := london.export(); exported.POI != "Camden Lock Books" || exported.CityDistrict != "London Borough of Camden" {
+		t.Fatalf("export() = %+v", exported)
+	}
+	// city levels still win over the new fallbacks
+	if got := (&address{City: "London", Borough: "Camden", Road: "High St", Neighbourhood: "Chalk Farm"}); got.getCity() != "London" || got.getStreet() != "High St" {
+		t.Fatalf("unexpected fallback for %+v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package posm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxLookupIDs is the most OSM IDs LocationIQ accepts in one lookup request
+const maxLookupIDs = 50
+
+// lookupConcurrency bounds the lookup requests in flight for one batch
+const lookupConcurrency = 4
+
+func GetPointsByLookup(tids []string) (map[string]*OsmPoint, map[string]error) {
+	return GetPointsByLookupContext(context.Background(), tids)
+}
+
+// GetPointsByLookupContext is like GetPointsByLookup but uses ctx for the upstream requests.
+// Every requested TID ends up in exactly one of the returned maps.
+func GetPointsByLookupContext(ctx context.Context, tids []string) (map[string]*OsmPoint, map[string]error) {
+	locations, errs := lookupMany(ctx, tids, nil)
+	points := make(map[string]*OsmPoint, len(locations))
+	for tid, location := range locations {
+		point, err := getOsmPointFromLocationIQResponse(location)
+		if err != nil {
+			errs[tid] = fmt.Errorf("getOsmPointFromLocationIQResponse error: %w", err)
+			continue
+		}
+		points[tid] = point
+	}
+	return points, errs
+}
+
+func GetCitiesByLookup(tids []string) (map[string]*OsmCity, map[string]error) {
+	return GetCitiesByLookupContext(context.Background(), tids)
+}
+
+// GetCitiesByLookupContext is like GetCitiesByLookup but uses ctx for the upstream requests.
+// Every requested TID ends up in exactly one of the returned maps.
+func GetCitiesByLookupContext(ctx context.Context, tids []string) (map[string]*OsmCity, map[string]error) {
+	locations, errs := lookupMany(ctx, tids, &SearchOptions{boundary: true})
+	cities := make(map[string]*OsmCity, len(locations))
+	for tid, location := range locations {
+		city, err := getOsmCityFromLocationIQResponse(location)
+		if err != nil {
+			errs[tid] = fmt.Errorf("getOsmCityFromLocationIQResponse error: %w", err)
+			continue
+		}
+		cities[tid] = city
+	}
+	return cities, errs
+}
+
+// lookupMany looks up the TIDs in chunks of maxLookupIDs, running chunks in parallel
+func lookupMany(ctx context.Context, tids []string, options *SearchOptions) (map[string]*locationIQResponse, map[string]error) {
+	results := make(map[string]*locationIQResponse)
+	errs := make(map[string]error)
+	pending := make([]string, 0, len(tids))
+	seen := make(map[string]struct{}, len(tids))
+	for _, tid := range tids {
+		if _, exists := seen[tid]; exists {
+			continue
+		}
+		seen[tid] = struct{}{}
+		if !IsOsmPlace(tid) {
+			errs[tid] = fmt.Errorf("invalid OSM TID %q", tid)
+			continue
+		}
+		pending = append(pending, tid)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lookupConcurrency)
+	for start := 0; start < len(pending); start += maxLookupIDs {
+		chunk := pending[start:min(start+maxLookupIDs, len(pending))]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			locations, err := lookupByOsmTIDs(ctx, chunk, options)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				for _, tid := range chunk {
+					errs[tid] = fmt.Errorf("lookup error: %w", err)
+				}
+				return
+			}
+			found := make(map[string]*locationIQResponse, len(locations))
+			for i := range locations {
+				found[locations[i].getPlaceID()] = &locations[i]
+			}
+			for _, tid := range chunk {
+				if location, ok := found[tid]; ok {
+					results[tid] = location
+				} else {
+					errs[tid] = fmt.Errorf("no results found")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return results, errs
+}
@@ -0,0 +1,65 @@
+package posm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchLookup(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		ids := strings.Split(r.URL.Query().Get("osm_ids"), ",")
+		if len(ids) > maxLookupIDs {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results := make([]map[string]any, 0, len(ids))
+		for _, id := range ids {
+			if id == "N404" {
+				continue
+			}
+			results = append(results, map[string]any{
+				"osm_type":     "node",
+				"osm_id":       id[1:],
+				"display_name": id,
+				"lat":          "37.7",
+				"lon":          "-122.4",
+				"address":      map[string]string{"city": "San Francisco", "state": "CA", "country_code": "us"},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	tids := []string{"N404", "bogus", "N1"}
+	for i := 2; i <= 120; i++ {
+		tids = append(tids, fmt.Sprintf("N%d", i))
+	}
+	tids = append(tids, "N1")
+
+	cities, errs := GetCitiesByLookup(tids)
+	if len(cities) != 120 {
+		t.Fatalf("expected 120 cities, got %d", len(cities))
+	}
+	if len(errs) != 2 || errs["N404"] == nil || errs["bogus"] == nil {
+		t.Fatalf("expected errors for missing and invalid TIDs, got %v", errs)
+	}
+	if cities["N57"] == nil || cities["N57"].Address != "San Francisco, CA" {
+		t.Fatalf("unexpected city for N57: %+v", cities["N57"])
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Fatalf("121 valid TIDs should be sent in 3 chunks, got %d requests", got)
+	}
+
+	points, errs := GetPointsByLookup([]string{"N7"})
+	if len(errs) != 0 || points["N7"] == nil || points["N7"].PlaceID != "N7" {
+		t.Fatalf("GetPointsByLookup failed: %v %v", points, errs)
+	}
+}
@@ -1,5 +1,73 @@
 package main
 
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/kaidev1024/posm"
+)
+
 func main() {
+	if len(os.Args) < 2 || os.Args[1] != "geocode" {
+		fmt.Fprintln(os.Stderr, "usage: posm geocode -in FILE -out FILE [-format csv|jsonl] [-workers N] [-checkpoint FILE] [-config FILE]")
+		os.Exit(2)
+	}
+	if err := geocode(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "posm:", err)
+		os.Exit(1)
+	}
+}
+
+// geocode runs a resumable batch geocoding job, the LocationIQ key comes from the config file or environment
+func geocode(args []string) error {
+	flags := flag.NewFlagSet("geocode", flag.ExitOnError)
+	configPath := flags.String("config", "", "JSON config file, environment variables override it")
+	inPath := flags.String("in", "", "input CSV or JSONL file")
+	outPath := flags.String("out", "", "output file, appended to when resuming")
+	format := flags.String("format", "", "csv or jsonl, defaults to the input file extension")
+	workers := flags.Int("workers", posm.DefaultBatchWorkers, "records geocoded concurrently")
+	checkpointPath := flags.String("checkpoint", "", "checkpoint file, defaults to OUT.checkpoint")
+	_ = flags.Parse(args)
+
+	if *inPath == "" || *outPath == "" {
+		return fmt.Errorf("-in and -out are required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*inPath)), ".")
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = *outPath + ".checkpoint"
+	}
+	if _, err := posm.LoadConfig(*configPath); err != nil {
+		return err
+	}
+
+	in, err := os.Open(*inPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(*outPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	stats, err := posm.GeocodeBatch(ctx, in, out, posm.BatchOptions{
+		Format:         *format,
+		Workers:        *workers,
+		CheckpointPath: *checkpointPath,
+	})
+	fmt.Fprintf(os.Stderr, "geocoded %d, failed %d, skipped %d\n", stats.Geocoded, stats.Failed, stats.Skipped)
+	if err != nil {
+		return fmt.Errorf("%w (rerun the same command to resume)", err)
+	}
+	return nil
 }
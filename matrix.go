@@ -0,0 +1,177 @@
+package posm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMatrixCoordinates is the most sources plus destinations sent in one matrix request
+const maxMatrixCoordinates = 25
+
+var matrixClient *Client
+
+// Matrix holds travel times and distances from every source (row) to every destination (column).
+// Unreachable pairs have Reachable false and zero duration and distance.
+type Matrix struct {
+	Durations [][]time.Duration
+	// Distances in meters
+	Distances [][]float64
+	Reachable [][]bool
+}
+
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+// PointCoordinates returns the locations of the points, for use with GetMatrix
+func PointCoordinates(points []*OsmPoint) []Coordinate {
+	coordinates := make([]Coordinate, len(points))
+	for i, point := range points {
+		coordinates[i] = point.Coordinate()
+	}
+	return coordinates
+}
+
+func GetMatrix(sources, destinations []Coordinate, profile string) (*Matrix, error) {
+	return GetMatrixContext(context.Background(), sources, destinations, profile)
+}
+
+// GetMatrixContext is like GetMatrix but uses ctx for the upstream requests.
+// Large inputs are split into blocks that fit the provider's coordinate limit.
+func GetMatrixContext(ctx context.Context, sources, destinations []Coordinate, profile string) (*Matrix, error) {
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 || len(destinations) == 0 {
+		return nil, fmt.Errorf("matrix needs at least one source and one destination")
+	}
+	for _, c := range append(append([]Coordinate{}, sources...), destinations...) {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+	matrix := &Matrix{
+		Durations: make([][]time.Duration, len(sources)),
+		Distances: make([][]float64, len(sources)),
+		Reachable: make([][]bool, len(sources)),
+	}
+	for i := range sources {
+		matrix.Durations[i] = make([]time.Duration, len(destinations))
+		matrix.Distances[i] = make([]float64, len(destinations))
+		matrix.Reachable[i] = make([]bool, len(destinations))
+	}
+
+	sourceChunk := min(len(sources), maxMatrixCoordinates/2)
+	destinationChunk := maxMatrixCoordinates - sourceChunk
+	for si := 0; si < len(sources); si += sourceChunk {
+		sourceBlock := sources[si:min(si+sourceChunk, len(sources))]
+		for di := 0; di < len(destinations); di += destinationChunk {
+			destinationBlock := destinations[di:min(di+destinationChunk, len(destinations))]
+			if err := matrix.fill(ctx, profile, sourceBlock, destinationBlock, si, di); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// fill requests one block and copies it into the matrix at row si and column di.
+// A block without a table is split in halves until the pairs without a route are isolated,
+// so only those are left unreachable.
+func (m *Matrix) fill(ctx context.Context, profile string, sources, destinations []Coordinate, si, di int) error {
+	table, err := table(ctx, profile, sources, destinations)
+	if errors.Is(err, ErrNoRoute) {
+		switch {
+		case len(sources) > 1 && len(sources) >= len(destinations):
+			half := len(sources) / 2
+			if err := m.fill(ctx, profile, sources[:half], destinations, si, di); err != nil {
+				return err
+			}
+			return m.fill(ctx, profile, sources[half:], destinations, si+half, di)
+		case len(destinations) > 1:
+			half := len(destinations) / 2
+			if err := m.fill(ctx, profile, sources, destinations[:half], si, di); err != nil {
+				return err
+			}
+			return m.fill(ctx, profile, sources, destinations[half:], si, di+half)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("table error: %w", err)
+	}
+	for i := range sources {
+		for j := range destinations {
+			duration := tableValue(table.Durations, i, j)
+			distance := tableValue(table.Distances, i, j)
+			if duration == nil {
+				continue
+			}
+			m.Reachable[si+i][di+j] = true
+			m.Durations[si+i][di+j] = seconds(*duration)
+			if distance != nil {
+				m.Distances[si+i][di+j] = *distance
+			}
+		}
+	}
+	return nil
+}
+
+// table requests one block of the matrix from LocationIQ or an OSRM server
+func table(ctx context.Context, profile string, sources, destinations []Coordinate) (*osrmTableResponse, error) {
+	params := url.Values{}
+	if !matrixClient.keyless {
+		params.Set("key", locationIQAccessToken)
+	}
+	params.Set("sources", indexList(0, len(sources)))
+	params.Set("destinations", indexList(len(sources), len(destinations)))
+	params.Set("annotations", "duration,distance")
+	coordinates := append(append([]Coordinate{}, sources...), destinations...)
+	path := "/" + profile + "/" + osrmCoordinates(coordinates)
+	resp, err := matrixClient.getPath(ctx, EndpointMatrix, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result osrmTableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := osrmError(result.Code, result.Message); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	return &result, nil
+}
+
+// indexList formats count consecutive indexes from start as "0;1;2"
+func indexList(start, count int) string {
+	indexes := make([]string, count)
+	for i := range indexes {
+		indexes[i] = strconv.Itoa(start + i)
+	}
+	return strings.Join(indexes, ";")
+}
+
+func tableValue(values [][]*float64, i, j int) *float64 {
+	if i >= len(values) || j >= len(values[i]) {
+		return nil
+	}
+	return values[i][j]
+}
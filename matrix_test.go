@@ -0,0 +1,122 @@
+package posm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetMatrix(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		parts := strings.Split(r.URL.Path, "/")
+		coordinates := strings.Split(parts[len(parts)-1], ";")
+		sources := strings.Split(r.URL.Query().Get("sources"), ";")
+		destinations := strings.Split(r.URL.Query().Get("destinations"), ";")
+		if len(coordinates) > maxMatrixCoordinates || len(sources)+len(destinations) != len(coordinates) {
+			t.Errorf("unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		// the duration encodes the source and destination latitudes, pairs with equal latitudes are unreachable
+		lat := func(index string) float64 {
+			i, _ := strconv.Atoi(index)
+			value, _ := strconv.ParseFloat(strings.Split(coordinates[i], ",")[1], 64)
+			return value
+		}
+		durations := make([][]*float64, len(sources))
+		distances := make([][]*float64, len(sources))
+		for i, source := range sources {
+			durations[i] = make([]*float64, len(destinations))
+			distances[i] = make([]*float64, len(destinations))
+			for j, destination := range destinations {
+				if lat(source) == lat(destination) {
+					continue
+				}
+				duration, distance := lat(source)*100+lat(destination), lat(source)*1000
+				durations[i][j], distances[i][j] = &duration, &distance
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "Ok", "durations": durations, "distances": distances})
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	sources := make([]*OsmPoint, 20)
+	for i := range sources {
+		sources[i] = &OsmPoint{Lat: float64(i), Lng: 1}
+	}
+	destinations := make([]Coordinate, 15)
+	for j := range destinations {
+		destinations[j] = Coordinate{Lat: float64(j), Lng: 2}
+	}
+	matrix, err := GetMatrix(PointCoordinates(sources), destinations, ProfileDriving)
+	if err != nil {
+		t.Fatalf("GetMatrix error: %v", err)
+	}
+	if requests != 4 {
+		t.Fatalf("expected 4 chunked requests, got %d", requests)
+	}
+	for i := range sources {
+		for j := range destinations {
+			if i == j {
+				if matrix.Reachable[i][j] || matrix.Durations[i][j] != 0 {
+					t.Fatalf("pair %d,%d should be unreachable", i, j)
+				}
+				continue
+			}
+			if !matrix.Reachable[i][j] || matrix.Durations[i][j] != time.Duration(i*100+j)*time.Second || matrix.Distances[i][j] != float64(i*1000) {
+				t.Fatalf("unexpected pair %d,%d: %v %v %v", i, j, matrix.Reachable[i][j], matrix.Durations[i][j], matrix.Distances[i][j])
+			}
+		}
+	}
+
+	if _, err := GetMatrix(nil, destinations, ProfileDriving); err == nil {
+		t.Fatalf("empty sources should be rejected")
+	}
+	if _, err := GetMatrix(destinations, destinations, "flying"); err == nil {
+		t.Fatalf("unknown profiles should be rejected")
+	}
+}
+
+func TestGetMatrixNoTable(t *testing.T) {
+	// the server has no table for any request that includes the island at latitude 5
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		coordinates := parts[len(parts)-1]
+		if strings.Contains(coordinates, ",5;") || strings.HasSuffix(coordinates, ",5") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"code": "NoTable", "message": "No table found"})
+			return
+		}
+		sources := strings.Split(r.URL.Query().Get("sources"), ";")
+		destinations := strings.Split(r.URL.Query().Get("destinations"), ";")
+		durations := make([][]float64, len(sources))
+		for i := range durations {
+			durations[i] = make([]float64, len(destinations))
+			for j := range durations[i] {
+				durations[i][j] = 60
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "Ok", "durations": durations, "distances": durations})
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	sources := []Coordinate{{Lat: 1, Lng: 1}, {Lat: 2, Lng: 1}, {Lat: 5, Lng: 1}, {Lat: 3, Lng: 1}}
+	destinations := []Coordinate{{Lat: 1, Lng: 2}, {Lat: 2, Lng: 2}, {Lat: 3, Lng: 2}}
+	matrix, err := GetMatrix(sources, destinations, ProfileDriving)
+	if err != nil {
+		t.Fatalf("NoTable should not fail the matrix: %v", err)
+	}
+	for i := range sources {
+		for j := range destinations {
+			if want := i != 2; matrix.Reachable[i][j] != want || (want && matrix.Durations[i][j] != time.Minute) {
+				t.Fatalf("pair %d,%d reachable=%v duration=%v", i, j, matrix.Reachable[i][j], matrix.Durations[i][j])
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package posm
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxNearbyRadius is the largest radius in meters LocationIQ accepts for nearby search
+const maxNearbyRadius = 30000
+
+func GetPointsNearby(lat, lng float64, tag string, radius int) ([]*OsmPoint, error) {
+	return GetPointsNearbyContext(context.Background(), lat, lng, tag, radius)
+}
+
+// GetPointsNearbyContext is like GetPointsNearby but uses ctx for the upstream request.
+// tag is an OSM class and type such as "amenity:cafe", radius is in meters.
+func GetPointsNearbyContext(ctx context.Context, lat, lng float64, tag string, radius int) ([]*OsmPoint, error) {
+	if err := (Coordinate{Lat: lat, Lng: lng}).validate(); err != nil {
+		return nil, err
+	}
+	if radius <= 0 || radius > maxNearbyRadius {
+		return nil, fmt.Errorf("invalid radius %d: must be between 1 and %d meters", radius, maxNearbyRadius)
+	}
+	var globalErr error
+	locations, err := nearby(ctx, lat, lng, tag, radius)
+	if err != nil {
+		if isUnableToGeocode(err) {
+			return []*OsmPoint{}, nil
+		}
+		return nil, fmt.Errorf("nearby error: %w", err)
+	}
+	points := make([]*OsmPoint, 0, len(locations))
+	for _, location := range locations {
+		point, err := getOsmPointFromLocationIQResponse(&location)
+		if err == nil {
+			points = append(points, point)
+		} else {
+			// log the error and continue with other results
+			globalErr = fmt.Errorf("getOsmPointFromLocationIQResponse error: %w", err)
+		}
+	}
+	return points, globalErr
+}
@@ -0,0 +1,44 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPointsNearby(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/nearby" || q.Get("tag") != "amenity:cafe" || q.Get("radius") != "1000" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":"Unable to geocode"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[
+			{"place_id":"1","osm_type":"node","osm_id":"501","name":"Blue Bottle","class":"amenity","type":"cafe","distance":120.5,"display_name":"Blue Bottle, Mint Plaza","lat":"37.78","lon":"-122.41","address":{"house_number":"66","road":"Mint St","city":"San Francisco","state":"CA","country_code":"us"}}
+		]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	points, err := GetPointsNearby(37.78, -122.41, "amenity:cafe", 1000)
+	if err != nil || len(points) != 1 {
+		t.Fatalf("GetPointsNearby failed: %v %v", points, err)
+	}
+	point := points[0]
+	if point.PlaceID != "N501" || point.Name != "Blue Bottle" || point.Category != "amenity:cafe" || point.Distance != 120.5 {
+		t.Fatalf("unexpected nearby point: %+v", point)
+	}
+	if !IsOsmPlace(point.PlaceID) {
+		t.Fatalf("nearby place IDs should be usable with GetPointByLookup")
+	}
+
+	empty, err := GetPointsNearby(0, 0, "amenity:bar", 1000)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("no results should return an empty slice: %v %v", empty, err)
+	}
+	if _, err := GetPointsNearby(37.78, -122.41, "amenity:cafe", 50000); err == nil {
+		t.Fatalf("radius above the provider limit should be rejected")
+	}
+}
@@ -0,0 +1,199 @@
+package posm
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LocationIQ regions
+const (
+	RegionUS = "us"
+	RegionEU = "eu"
+)
+
+// DefaultTimeout bounds every upstream request unless overridden with WithTimeout
+const DefaultTimeout = 10 * time.Second
+
+// Middleware wraps the transport used for upstream requests
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// Option configures the clients built by Init
+type Option func(*options)
+
+type options struct {
+	timeout          time.Duration
+	endpointTimeouts map[string]time.Duration
+	userAgent        string
+	proxyURL         *url.URL
+	headers          http.Header
+	middlewares      []Middleware
+	region           string
+	rateLimit        float64
+	cacheSize        int
+	home             Coordinate
+	stateNames       StateNames
+	routingURL       string
+	matrixURL        string
+}
+
+// WithTimeout sets the overall timeout of each request, zero disables it
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithEndpointTimeout overrides the timeout for one endpoint, e.g. EndpointAutocomplete
+func WithEndpointTimeout(endpoint string, timeout time.Duration) Option {
+	return func(o *options) {
+		o.endpointTimeouts[endpoint] = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent upstream
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithProxy sends all requests through the given outbound proxy
+func WithProxy(proxyURL *url.URL) Option {
+	return func(o *options) {
+		o.proxyURL = proxyURL
+	}
+}
+
+// WithHeader adds an extra header to every request
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.headers.Add(key, value)
+	}
+}
+
+// WithMiddleware appends transport middlewares, the first one added runs outermost
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithRegion selects the LocationIQ region, RegionUS or RegionEU
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithRateLimit caps upstream requests per second across all endpoints, zero disables it
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(o *options) {
+		o.rateLimit = requestsPerSecond
+	}
+}
+
+// WithCacheSize keeps up to size successful responses in memory, zero disables caching
+func WithCacheSize(size int) Option {
+	return func(o *options) {
+		o.cacheSize = size
+	}
+}
+
+// WithHomeLocation sets the reference point used by WithHomeProximity
+func WithHomeLocation(lat, lng float64) Option {
+	return func(o *options) {
+		o.home = Coordinate{Lat: lat, Lng: lng}
+	}
+}
+
+// WithStateNames abbreviates or expands US, Canadian and Australian state names in formatted addresses
+func WithStateNames(names StateNames) Option {
+	return func(o *options) {
+		o.stateNames = names
+	}
+}
+
+// WithRoutingURL sends directions requests to an OSRM-compatible server instead of LocationIQ,
+// e.g. "http://localhost:5000/route/v1". The LocationIQ key is not sent to it.
+func WithRoutingURL(baseURL string) Option {
+	return func(o *options) {
+		o.routingURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithMatrixURL sends matrix requests to an OSRM-compatible server instead of LocationIQ,
+// e.g. "http://localhost:5000/table/v1". The LocationIQ key is not sent to it.
+func WithMatrixURL(baseURL string) Option {
+	return func(o *options) {
+		o.matrixURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		timeout:          DefaultTimeout,
+		endpointTimeouts: make(map[string]time.Duration),
+		headers:          make(http.Header),
+		region:           RegionUS,
+		home:             Coordinate{Lat: HEADQUARTER_LAT, Lng: HEADQUARTER_LNG},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// baseURL returns the regional URL of a LocationIQ API path
+func (o *options) baseURL(path string) string {
+	host := "us1.locationiq.com"
+	if o.region == RegionEU {
+		host = "eu1.locationiq.com"
+	}
+	return "https://" + host + path
+}
+
+// transport builds the round tripper shared by all endpoint clients
+func (o *options) transport() http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if o.proxyURL != nil {
+		base.Proxy = http.ProxyURL(o.proxyURL)
+	}
+	var rt http.RoundTripper = base
+	if o.userAgent != "" || len(o.headers) > 0 {
+		rt = &headerTransport{next: rt, userAgent: o.userAgent, headers: o.headers}
+	}
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		rt = o.middlewares[i](rt)
+	}
+	return rt
+}
+
+func (o *options) httpClient(endpoint string, transport http.RoundTripper) *http.Client {
+	timeout := o.timeout
+	if t, ok := o.endpointTimeouts[endpoint]; ok {
+		timeout = t
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// headerTransport sets the configured headers on each outgoing request
+type headerTransport struct {
+	next      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
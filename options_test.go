@@ -0,0 +1,74 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestInitOptions(t *testing.T) {
+	var gotUserAgent, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserAgent = r.Header.Get("User-Agent")
+		gotHeader = r.Header.Get("X-Team")
+		_, _ = fmt.Fprint(w, `[ {"place_id":"1","display_name":"City","lat":"1","lon":"2","address":{"city":"SF","state":"CA"}} ]`)
+	}))
+	defer server.Close()
+
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	Init("key",
+		WithTimeout(5*time.Second),
+		WithEndpointTimeout(EndpointAutocomplete, time.Second),
+		WithUserAgent("posm-test"),
+		WithHeader("X-Team", "geo"),
+		WithMiddleware(tag("outer"), tag("inner")),
+	)
+	if searchClient.HTTPClient.Timeout != 5*time.Second || lookupClient.HTTPClient.Timeout != 5*time.Second {
+		t.Fatalf("overall timeout not applied")
+	}
+	if autoCompleteClient.HTTPClient.Timeout != time.Second {
+		t.Fatalf("endpoint timeout not applied")
+	}
+
+	searchClient.BaseURL = server.URL + "/search"
+	if _, err := GetCityBySearch("sf"); err != nil {
+		t.Fatalf("GetCityBySearch failed: %v", err)
+	}
+	if gotUserAgent != "posm-test" || gotHeader != "geo" {
+		t.Fatalf("headers not sent: user-agent=%q x-team=%q", gotUserAgent, gotHeader)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("middlewares ran in wrong order: %v", order)
+	}
+}
+
+func TestProxyOption(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	o := newOptions([]Option{WithProxy(proxyURL)})
+	transport := o.transport().(*http.Transport)
+	req, _ := http.NewRequest(http.MethodGet, "https://us1.locationiq.com/v1/search", nil)
+	got, err := transport.Proxy(req)
+	if err != nil || got.String() != proxyURL.String() {
+		t.Fatalf("proxy not applied: %v %v", got, err)
+	}
+	if newOptions(nil).timeout != DefaultTimeout {
+		t.Fatalf("default timeout not applied")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
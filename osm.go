@@ -1,31 +1,77 @@
 package posm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 var searchClient *Client
 var autoCompleteClient *Client
 var lookupClient *Client
+var reverseClient *Client
+var nearbyClient *Client
 var locationIQAccessToken string
+var homeLocation = Coordinate{Lat: HEADQUARTER_LAT, Lng: HEADQUARTER_LNG}
+
+// get sends a GET request to the client's endpoint, serving it from the cache when possible
+// and otherwise counting it against the usage budget and rate limit
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values) (*http.Response, error) {
+	return c.getPath(ctx, endpoint, "", params)
+}
+
+// getPath is like get but appends path to the client's base URL first
+func (c *Client) getPath(ctx context.Context, endpoint, path string, params url.Values) (*http.Response, error) {
+	reqURL := c.BaseURL + path + "?" + params.Encode()
+	if resp, ok := cache.get(reqURL); ok {
+		return resp, nil
+	}
+	// check the budget first so a rejected call does not take a rate limit slot
+	tracker, caller := usageTracker, CallerTag(ctx)
+	if tracker != nil {
+		if err := tracker.reserve(endpoint, caller); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err == nil {
+		err = limiter.wait(ctx)
+	}
+	if err != nil {
+		if tracker != nil {
+			tracker.release(endpoint, caller)
+		}
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return cache.store(reqURL, resp)
+}
 
 // searchText search for OSM location by text, returns the first result
-func searchText(query string) (*locationIQResponse, error) {
+func searchText(ctx context.Context, query string, options *SearchOptions) (*locationIQResponse, error) {
 	params := url.Values{}
 	params.Set("key", locationIQAccessToken)
 	params.Set("format", "json")
 	params.Set("addressdetails", "1")
 	params.Set("q", query)
-	reqURL := searchClient.BaseURL + "?" + params.Encode()
-	resp, err := searchClient.HTTPClient.Get(reqURL)
+	options.apply(params)
+	options.applyDetails(params)
+	resp, err := searchClient.get(ctx, EndpointSearch, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return pickLocation(nil)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
 	}
@@ -33,6 +79,14 @@ func searchText(query string) (*locationIQResponse, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return pickLocation(options.rank(results))
+}
+
+// pickLocation returns the first result that resolves to a city, or the first result
+func pickLocation(results []locationIQResponse) (*locationIQResponse, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
 	for _, result := range results {
 		if result.Address.getCity() != "" {
 			return &result, nil
@@ -42,14 +96,15 @@ func searchText(query string) (*locationIQResponse, error) {
 }
 
 // searchTextMany search for OSM location by text, return all results
-func searchTextMany(query string) ([]locationIQResponse, error) {
+func searchTextMany(ctx context.Context, query string, options *SearchOptions) ([]locationIQResponse, error) {
 	params := url.Values{}
 	params.Set("key", locationIQAccessToken)
 	params.Set("format", "json")
 	params.Set("addressdetails", "1")
 	params.Set("q", query)
-	reqURL := searchClient.BaseURL + "?" + params.Encode()
-	resp, err := searchClient.HTTPClient.Get(reqURL)
+	options.apply(params)
+	options.applyDetails(params)
+	resp, err := searchClient.get(ctx, EndpointSearch, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
@@ -65,19 +120,24 @@ func searchTextMany(query string) ([]locationIQResponse, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-	return results, nil
+	return options.rank(results), nil
 }
 
-// autocomplete search for OSM location by text, return all results
-func autocomplete(query string) ([]locationIQResponse, error) {
+// searchStructured search for OSM location by address components, return all results
+func searchStructured(ctx context.Context, query StructuredQuery, options *SearchOptions) ([]locationIQResponse, error) {
 	params := url.Values{}
 	params.Set("key", locationIQAccessToken)
 	params.Set("format", "json")
-	params.Set("dedupe", "1")
-	params.Set("limit", "10")
-	params.Set("q", query)
-	reqURL := autoCompleteClient.BaseURL + "?" + params.Encode()
-	resp, err := autoCompleteClient.HTTPClient.Get(reqURL)
+	params.Set("addressdetails", "1")
+	setIfNotEmpty(params, "street", query.Street)
+	setIfNotEmpty(params, "city", query.City)
+	setIfNotEmpty(params, "county", query.County)
+	setIfNotEmpty(params, "state", query.State)
+	setIfNotEmpty(params, "country", query.Country)
+	setIfNotEmpty(params, "postalcode", query.PostalCode)
+	options.apply(params)
+	options.applyDetails(params)
+	resp, err := searchClient.get(ctx, EndpointSearch, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
@@ -93,22 +153,28 @@ func autocomplete(query string) ([]locationIQResponse, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-	return results, nil
+	return options.rank(results), nil
 }
 
-// lookupByOsmTID search for OSM location by OSM IDs
-func lookupByOsmTID(osmTID string) (*locationIQResponse, error) {
+// autocomplete search for OSM location by text, return all results, tag restricts the OSM classes matched
+func autocomplete(ctx context.Context, query, tag string, options *SearchOptions) ([]locationIQResponse, error) {
 	params := url.Values{}
 	params.Set("key", locationIQAccessToken)
 	params.Set("format", "json")
-	params.Set("osm_ids", osmTID)
-	reqURL := lookupClient.BaseURL + "?" + params.Encode()
-	resp, err := lookupClient.HTTPClient.Get(reqURL)
+	params.Set("dedupe", "1")
+	params.Set("limit", strconv.Itoa(defaultAutocompleteLimit))
+	params.Set("q", query)
+	setIfNotEmpty(params, "tag", tag)
+	options.apply(params)
+	resp, err := autoCompleteClient.get(ctx, EndpointAutocomplete, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return []locationIQResponse{}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
 	}
@@ -116,8 +182,95 @@ func lookupByOsmTID(osmTID string) (*locationIQResponse, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return options.rank(results), nil
+}
+
+// lookupByOsmTID search for OSM location by OSM IDs
+func lookupByOsmTID(ctx context.Context, osmTID string, options *SearchOptions) (*locationIQResponse, error) {
+	results, err := lookupByOsmTIDs(ctx, []string{osmTID}, options)
+	if err != nil {
+		return nil, err
+	}
 	if len(results) == 0 {
 		return nil, fmt.Errorf("no results found")
 	}
 	return &results[0], nil
 }
+
+// lookupByOsmTIDs search for OSM locations by a list of OSM IDs, return all results
+func lookupByOsmTIDs(ctx context.Context, osmTIDs []string, options *SearchOptions) ([]locationIQResponse, error) {
+	params := url.Values{}
+	params.Set("key", locationIQAccessToken)
+	params.Set("format", "json")
+	params.Set("osm_ids", strings.Join(osmTIDs, ","))
+	options.applyDetails(params)
+	resp, err := lookupClient.get(ctx, EndpointLookup, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	var results []locationIQResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return results, nil
+}
+
+// reverse search for the OSM location at the coordinates, zoom sets the level of detail
+func reverse(ctx context.Context, lat, lng float64, zoom int, options *SearchOptions) (*locationIQResponse, error) {
+	params := url.Values{}
+	params.Set("key", locationIQAccessToken)
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', -1, 64))
+	params.Set("zoom", strconv.Itoa(zoom))
+	options.applyDetails(params)
+	resp, err := reverseClient.get(ctx, EndpointReverse, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	var result locationIQResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// nearby search for OSM points of interest around the coordinates, tag filters by OSM class and type
+func nearby(ctx context.Context, lat, lng float64, tag string, radius int) ([]locationIQResponse, error) {
+	params := url.Values{}
+	params.Set("key", locationIQAccessToken)
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', -1, 64))
+	params.Set("radius", strconv.Itoa(radius))
+	setIfNotEmpty(params, "tag", tag)
+	resp, err := nearbyClient.get(ctx, EndpointNearby, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []locationIQResponse{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	var results []locationIQResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return results, nil
+}
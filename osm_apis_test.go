@@ -1,6 +1,7 @@
 package posm
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -9,9 +10,16 @@ import (
 
 func setupClientsForServer(server *httptest.Server) {
 	locationIQAccessToken = "test-key"
+	cache = nil
+	limiter = nil
 	searchClient = &Client{BaseURL: server.URL + "/search", HTTPClient: server.Client()}
 	autoCompleteClient = &Client{BaseURL: server.URL + "/autocomplete", HTTPClient: server.Client()}
 	lookupClient = &Client{BaseURL: server.URL + "/lookup", HTTPClient: server.Client()}
+	reverseClient = &Client{BaseURL: server.URL + "/reverse", HTTPClient: server.Client()}
+	nearbyClient = &Client{BaseURL: server.URL + "/nearby", HTTPClient: server.Client()}
+	timezoneClient = &Client{BaseURL: server.URL + "/timezone", HTTPClient: server.Client()}
+	directionsClient = &Client{BaseURL: server.URL + "/directions", HTTPClient: server.Client()}
+	matrixClient = &Client{BaseURL: server.URL + "/matrix", HTTPClient: server.Client()}
 }
 
 func TestInit(t *testing.T) {
@@ -19,7 +27,7 @@ func TestInit(t *testing.T) {
 	if locationIQAccessToken != "abc123" {
 		t.Fatalf("Init did not set access token")
 	}
-	if searchClient == nil || autoCompleteClient == nil || lookupClient == nil {
+	if searchClient == nil || autoCompleteClient == nil || lookupClient == nil || reverseClient == nil || nearbyClient == nil || timezoneClient == nil || directionsClient == nil || matrixClient == nil {
 		t.Fatalf("Init did not initialize all clients")
 	}
 }
@@ -61,25 +69,25 @@ func TestOsmHTTPFunctions(t *testing.T) {
 	setupClientsForServer(server)
 
 	// searchText
-	resp, err := searchText("pick-city")
+	resp, err := searchText(context.Background(), "pick-city", nil)
 	if err != nil || resp.PlaceID != "2" {
 		t.Fatalf("searchText failed: resp=%+v err=%v", resp, err)
 	}
 
 	// searchTextMany (404 => empty)
-	results, err := searchTextMany("unknown")
+	results, err := searchTextMany(context.Background(), "unknown", nil)
 	if err != nil || len(results) != 0 {
 		t.Fatalf("searchTextMany 404 handling failed: len=%d err=%v", len(results), err)
 	}
 
 	// autocomplete non-200
-	_, err = autocomplete("boom")
+	_, err = autocomplete(context.Background(), "boom", "", nil)
 	if err == nil {
 		t.Fatalf("autocomplete should fail on non-200")
 	}
 
 	// lookupByOsmTID empty result
-	_, err = lookupByOsmTID("EMPTY")
+	_, err = lookupByOsmTID(context.Background(), "EMPTY", nil)
 	if err == nil {
 		t.Fatalf("lookupByOsmTID should fail on empty results")
 	}
@@ -178,6 +186,159 @@ func TestAPIFunctions(t *testing.T) {
 	}
 }
 
+func TestStructuredSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("street") == "10 Market St" && q.Get("postalcode") == "94105":
+			_, _ = fmt.Fprint(w, `[ {"place_id":"s1","osm_type":"way","osm_id":"7","display_name":"Market","lat":"37.79","lon":"-122.39","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}} ]`)
+		case q.Get("q") == "1 Nowhere Rd, Springfield, US":
+			_, _ = fmt.Fprint(w, `[ {"place_id":"f1","osm_type":"node","osm_id":"8","display_name":"Fallback","lat":"39.8","lon":"-89.6","address":{"road":"Nowhere Rd","city":"Springfield","state":"IL","country_code":"us"}} ]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupClientsForServer(server)
+
+	point, err := GetPointByStructuredSearch(StructuredQuery{Street: "10 Market St", City: "San Francisco", State: "CA", PostalCode: "94105", Country: "US"})
+	if err != nil || point.PlaceID != "W7" || point.Address != "10 Market St, San Francisco, CA" {
+		t.Fatalf("GetPointByStructuredSearch failed: point=%+v err=%v", point, err)
+	}
+
+	street, err := GetStreetByStructuredSearch(StructuredQuery{Street: "1 Nowhere Rd", City: "Springfield", Country: "US"})
+	if err != nil || street.PlaceID != "N8" || street.Address != "Nowhere Rd, Springfield, IL" {
+		t.Fatalf("GetStreetByStructuredSearch should fall back to free text: street=%+v err=%v", street, err)
+	}
+
+	if _, err := GetPointByStructuredSearch(StructuredQuery{City: " "}); err == nil {
+		t.Fatalf("empty structured query should fail")
+	}
+}
+
+func TestPointsBySearchFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "1 Pariser Platz, Berlin":
+			_, _ = fmt.Fprint(w, `[ {"place_id":"d1","osm_type":"way","osm_id":"1","display_name":"Pariser Platz, Mitte, Berlin, 10117, Deutschland","lat":"52.51","lon":"13.38","address":{"house_number":"1","road":"Pariser Platz","city":"Berlin","postcode":"10117","country_code":"de"}} ]`)
+		case "10 Market St":
+			_, _ = fmt.Fprint(w, `[
+				{"place_id":"u1","osm_type":"node","osm_id":"4","display_name":"10, Market Street, San Francisco, California, 94105, United States","lat":"37.79","lon":"-122.39","address":{"house_number":"10","road":"Market Street","city":"San Francisco","state":"California","postcode":"94105","country_code":"us"}},
+				{"place_id":"u2","osm_type":"node","osm_id":"5","display_name":"100, Market Street, San Francisco, California, 94105, United States","lat":"37.79","lon":"-122.39","address":{"house_number":"100","road":"Market Street","city":"San Francisco","state":"California","postcode":"94105","country_code":"us"}},
+				{"place_id":"u3","osm_type":"node","osm_id":"6","display_name":"5, Broadway, New York, New York, 10004, United States","lat":"40.70","lon":"-74.01","address":{"house_number":"5","road":"Broadway","city":"New York","state":"New York","postcode":"10004","country_code":"us"}}
+			]`)
+		case "Nishi-Shinjuku", "8-1 Nishi":
+			_, _ = fmt.Fprint(w, `[
+				{"place_id":"j1","osm_type":"way","osm_id":"2","display_name":"Tokyo Metropolitan Government Building, Shinjuku, Tokyo, Japan","lat":"35.68","lon":"139.69","address":{"house_number":"8-1","quarter":"Nishi-Shinjuku 2-chome","city":"Shinjuku","state":"Tokyo","postcode":"160-0023","country_code":"jp"}},
+				{"place_id":"j2","osm_type":"node","osm_id":"3","display_name":"Kabukicho, Shinjuku, Tokyo, Japan","lat":"35.69","lon":"139.70","address":{"house_number":"1-1","quarter":"Kabukicho 1-chome","city":"Shinjuku","state":"Tokyo","postcode":"160-0021","country_code":"jp"}}
+			]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupClientsForServer(server)
+
+	// the formatted addresses start with the street or the postcode, the query prefix must still match
+	// results whose house number, street or name do not match the leading word are dropped
+	for query, want := range map[string]string{"1 Pariser Platz, Berlin": "W1", "Nishi-Shinjuku": "W2", "8-1 Nishi": "W2", "10 Market St": "N4"} {
+		points, err := GetPointsBySearch(query)
+		if err != nil || len(points) != 1 || points[0].PlaceID != want {
+			t.Fatalf("GetPointsBySearch(%q) = %+v, %v, want only %s", query, points, err, want)
+		}
+	}
+}
+
+func TestPointAndStreetAutocomplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("q") == "none" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":"Unable to geocode"}`)
+			return
+		}
+		switch q.Get("tag") {
+		case autocompleteTagPoints:
+			_, _ = fmt.Fprint(w, `[
+				{"place_id":"a1","osm_type":"node","osm_id":"1","display_name":"10 Market St","lat":"1","lon":"2","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}},
+				{"place_id":"a2","osm_type":"node","osm_id":"2","display_name":"10 Market St dup","lat":"1","lon":"2","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}},
+				{"place_id":"a3","osm_type":"relation","osm_id":"3","display_name":"San Francisco","lat":"1","lon":"2","address":{"city":"San Francisco","state":"CA","country_code":"us"}}
+			]`)
+		case autocompleteTagStreets:
+			_, _ = fmt.Fprint(w, `[
+				{"place_id":"b1","osm_type":"way","osm_id":"4","display_name":"Market St","lat":"1","lon":"2","address":{"road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}},
+				{"place_id":"b2","osm_type":"way","osm_id":"5","display_name":"Market St","lat":"1","lon":"2","address":{"road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}},
+				{"place_id":"b3","osm_type":"way","osm_id":"6","display_name":"Market St, Oakland","lat":"1","lon":"2","address":{"road":"Market St","city":"Oakland","state":"CA","country_code":"us"}}
+			]`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	setupClientsForServer(server)
+
+	points, err := GetPointsByAutocomplete("10 market")
+	if err != nil || len(points) != 1 || points[0].PlaceID != "N1" {
+		t.Fatalf("GetPointsByAutocomplete should dedupe and skip cities: points=%+v err=%v", points, err)
+	}
+
+	streets, err := GetStreetsByAutocomplete("market")
+	if err != nil || len(streets) != 2 || streets[1].Address != "Market St, Oakland, CA" {
+		t.Fatalf("GetStreetsByAutocomplete should dedupe: streets=%+v err=%v", streets, err)
+	}
+
+	emptyPoints, err := GetPointsByAutocomplete("none")
+	if err != nil || len(emptyPoints) != 0 {
+		t.Fatalf("GetPointsByAutocomplete should return empty slice when unable to geocode: %v %v", emptyPoints, err)
+	}
+}
+
+func TestReverseFunctions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/reverse" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("lat") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":"Unable to geocode"}`)
+			return
+		}
+		switch q.Get("zoom") {
+		case "18":
+			_, _ = fmt.Fprint(w, `{"place_id":"r1","osm_type":"way","osm_id":"42","display_name":"10 Market St","lat":"37.79","lon":"-122.39","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}}`)
+		case "10":
+			_, _ = fmt.Fprint(w, `{"place_id":"r2","osm_type":"relation","osm_id":"111968","display_name":"San Francisco","lat":"37.77","lon":"-122.41","address":{"city":"San Francisco","state":"CA","country_code":"us"}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	setupClientsForServer(server)
+
+	point, err := GetPointByCoordinates(37.79, -122.39)
+	if err != nil || point.PlaceID != "W42" || point.Address != "10 Market St, San Francisco, CA" {
+		t.Fatalf("GetPointByCoordinates failed: point=%+v err=%v", point, err)
+	}
+	if point.StreetSearchText != "Market St, San Francisco, CA, us" || point.CitySearchText != "San Francisco, CA, us" {
+		t.Fatalf("GetPointByCoordinates search texts: %+v", point)
+	}
+
+	city, err := GetCityByCoordinates(37.79, -122.39)
+	if err != nil || city.PlaceID != "R111968" || city.Address != "San Francisco, CA" {
+		t.Fatalf("GetCityByCoordinates failed: city=%+v err=%v", city, err)
+	}
+
+	if _, err := GetPointByCoordinates(0, 0); err == nil {
+		t.Fatalf("GetPointByCoordinates should fail when nothing is found")
+	}
+}
+
 func TestConvertersAndErrorHelpers(t *testing.T) {
 	point, err := getOsmPointFromLocationIQResponse(&locationIQResponse{
 		PlaceID:     "1",
@@ -208,3 +369,22 @@ func TestConvertersAndErrorHelpers(t *testing.T) {
 		t.Fatalf("isUnableToGeocode should return false for unrelated errors")
 	}
 }
+
+func TestGetByCoordinatesValidation(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	if _, err := GetPointByCoordinates(91, 0); err == nil {
+		t.Fatalf("out of range latitudes should be rejected")
+	}
+	if _, err := GetCityByCoordinates(0, -181); err == nil {
+		t.Fatalf("out of range longitudes should be rejected")
+	}
+	if requests != 0 {
+		t.Fatalf("invalid coordinates should not reach the server")
+	}
+}
@@ -0,0 +1,341 @@
+package posm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Kind is the granularity of a place
+type Kind string
+
+// Place kinds, from the most to the least precise
+const (
+	KindHouse        Kind = "house"
+	KindPOI          Kind = "poi"
+	KindStreet       Kind = "street"
+	KindPostcode     Kind = "postcode"
+	KindNeighborhood Kind = "neighborhood"
+	KindCity         Kind = "city"
+	KindCounty       Kind = "county"
+	KindState        Kind = "state"
+	KindCountry      Kind = "country"
+)
+
+// Place is any geocoding result, fields that do not apply to its Kind are left empty
+type Place struct {
+	PlaceID           string        `json:"place_id"`
+	Kind              Kind          `json:"kind"`
+	Lat               float64       `json:"lat"`
+	Lng               float64       `json:"lng"`
+	DisplayName       string        `json:"display_name"`
+	Address           string        `json:"address"`
+	StructuredAddress *Address      `json:"structured_address,omitempty"`
+	BBox              *BBox         `json:"bbox,omitempty"`
+	Details           *PlaceDetails `json:"details,omitempty"`
+	Name              string        `json:"name,omitempty"`
+	Category          string        `json:"category,omitempty"`
+	// Distance in meters from the reference point of a nearby search
+	Distance         float64   `json:"distance,omitempty"`
+	StreetSearchText string    `json:"street_search_text,omitempty"`
+	CitySearchText   string    `json:"city_search_text,omitempty"`
+	Timezone         *Timezone `json:"timezone,omitempty"`
+	// Boundary is the outline of places mapped as areas, nil for single nodes
+	Boundary *Boundary `json:"boundary,omitempty"`
+}
+
+// placer is implemented by OsmCity, OsmPoint and OsmStreet
+type placer interface {
+	Place() *Place
+}
+
+// PlaceOf converts the result of any single-result Get function,
+// e.g. PlaceOf(GetCityBySearch("Paris"))
+func PlaceOf[T placer](result T, err error) (*Place, error) {
+	return result.Place(), err
+}
+
+// PlacesOf converts the results of any Get function returning a slice,
+// e.g. PlacesOf(GetPointsBySearch("cafe"))
+func PlacesOf[T placer](results []T, err error) ([]*Place, error) {
+	if results == nil {
+		return nil, err
+	}
+	places := make([]*Place, len(results))
+	for i, result := range results {
+		places[i] = result.Place()
+	}
+	return places, err
+}
+
+// PlacesByIDOf converts the results of the batch lookup functions,
+// e.g. PlacesByIDOf(GetPointsByLookup(ids))
+func PlacesByIDOf[T placer](results map[string]T, errs map[string]error) (map[string]*Place, map[string]error) {
+	places := make(map[string]*Place, len(results))
+	for id, result := range results {
+		places[id] = result.Place()
+	}
+	return places, errs
+}
+
+// Place converts the city, returning nil for a nil city
+func (c *OsmCity) Place() *Place {
+	if c == nil {
+		return nil
+	}
+	return &Place{
+		PlaceID:           c.PlaceID,
+		Kind:              KindCity,
+		Lat:               c.Lat,
+		Lng:               c.Lng,
+		DisplayName:       c.DisplayName,
+		Address:           c.Address,
+		StructuredAddress: c.StructuredAddress,
+		BBox:              c.BBox,
+		Details:           c.Details,
+		Timezone:          c.Timezone,
+		Boundary:          c.Boundary,
+	}
+}
+
+// Place converts the point, its Kind is derived from the OSM category and address
+func (p *OsmPoint) Place() *Place {
+	if p == nil {
+		return nil
+	}
+	class, placeType, _ := strings.Cut(p.Category, ":")
+	return &Place{
+		PlaceID:           p.PlaceID,
+		Kind:              placeKind(class, placeType, p.StructuredAddress),
+		Lat:               p.Lat,
+		Lng:               p.Lng,
+		DisplayName:       p.DisplayName,
+		Address:           p.Address,
+		StructuredAddress: p.StructuredAddress,
+		BBox:              p.BBox,
+		Details:           p.Details,
+		Name:              p.Name,
+		Category:          p.Category,
+		Distance:          p.Distance,
+		StreetSearchText:  p.StreetSearchText,
+		CitySearchText:    p.CitySearchText,
+	}
+}
+
+// Place converts the street, returning nil for a nil street
+func (s *OsmStreet) Place() *Place {
+	if s == nil {
+		return nil
+	}
+	return &Place{
+		PlaceID:           s.PlaceID,
+		Kind:              KindStreet,
+		Lat:               s.Lat,
+		Lng:               s.Lng,
+		DisplayName:       s.DisplayName,
+		Address:           s.Address,
+		StructuredAddress: s.StructuredAddress,
+		BBox:              s.BBox,
+		Details:           s.Details,
+	}
+}
+
+// City converts the place to an OsmCity, dropping point-only fields
+func (p *Place) City() *OsmCity {
+	if p == nil {
+		return nil
+	}
+	return &OsmCity{
+		PlaceID:           p.PlaceID,
+		Lat:               p.Lat,
+		Lng:               p.Lng,
+		DisplayName:       p.DisplayName,
+		Address:           p.Address,
+		StructuredAddress: p.StructuredAddress,
+		BBox:              p.BBox,
+		Details:           p.Details,
+		Timezone:          p.Timezone,
+		Boundary:          p.Boundary,
+	}
+}
+
+// Point converts the place to an OsmPoint, dropping city-only fields
+func (p *Place) Point() *OsmPoint {
+	if p == nil {
+		return nil
+	}
+	return &OsmPoint{
+		PlaceID:           p.PlaceID,
+		Lat:               p.Lat,
+		Lng:               p.Lng,
+		DisplayName:       p.DisplayName,
+		Address:           p.Address,
+		StructuredAddress: p.StructuredAddress,
+		StreetSearchText:  p.StreetSearchText,
+		CitySearchText:    p.CitySearchText,
+		BBox:              p.BBox,
+		Details:           p.Details,
+		Name:              p.Name,
+		Category:          p.Category,
+		Distance:          p.Distance,
+	}
+}
+
+// Street converts the place to an OsmStreet
+func (p *Place) Street() *OsmStreet {
+	if p == nil {
+		return nil
+	}
+	return &OsmStreet{
+		PlaceID:           p.PlaceID,
+		Lat:               p.Lat,
+		Lng:               p.Lng,
+		DisplayName:       p.DisplayName,
+		Address:           p.Address,
+		StructuredAddress: p.StructuredAddress,
+		BBox:              p.BBox,
+		Details:           p.Details,
+	}
+}
+
+// Coordinate returns the place's location
+func (p *Place) Coordinate() Coordinate {
+	return Coordinate{Lat: p.Lat, Lng: p.Lng}
+}
+
+func GetPlaceBySearch(text string, opts ...SearchOption) (*Place, error) {
+	return GetPlaceBySearchContext(context.Background(), text, opts...)
+}
+
+// GetPlaceBySearchContext is like GetPlaceBySearch but uses ctx for the upstream request.
+// The best match is returned whatever its kind.
+func GetPlaceBySearchContext(ctx context.Context, text string, opts ...SearchOption) (*Place, error) {
+	options, err := newSearchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	options.boundary = true
+	location, err := searchText(ctx, text, options)
+	if err != nil {
+		return nil, fmt.Errorf("searchText error: %w", err)
+	}
+	return getPlaceFromLocationIQResponse(location)
+}
+
+func GetPlaceByLookup(tid string) (*Place, error) {
+	return GetPlaceByLookupContext(context.Background(), tid)
+}
+
+// GetPlaceByLookupContext is like GetPlaceByLookup but uses ctx for the upstream request
+func GetPlaceByLookupContext(ctx context.Context, tid string) (*Place, error) {
+	location, err := lookupByOsmTID(ctx, tid, &SearchOptions{boundary: true})
+	if err != nil {
+		return nil, fmt.Errorf("lookup error: %w", err)
+	}
+	return getPlaceFromLocationIQResponse(location)
+}
+
+func GetPlaceByCoordinates(lat, lng float64) (*Place, error) {
+	return GetPlaceByCoordinatesContext(context.Background(), lat, lng)
+}
+
+// GetPlaceByCoordinatesContext is like GetPlaceByCoordinates but uses ctx for the upstream request
+func GetPlaceByCoordinatesContext(ctx context.Context, lat, lng float64) (*Place, error) {
+	if err := (Coordinate{Lat: lat, Lng: lng}).validate(); err != nil {
+		return nil, err
+	}
+	location, err := reverse(ctx, lat, lng, reverseZoomAddress, &SearchOptions{boundary: true})
+	if err != nil {
+		return nil, fmt.Errorf("reverse error: %w", err)
+	}
+	return getPlaceFromLocationIQResponse(location)
+}
+
+func getPlaceFromLocationIQResponse(resp *locationIQResponse) (*Place, error) {
+	var globalErr error
+	point, err := getOsmPointFromLocationIQResponse(resp)
+	if err != nil {
+		globalErr = fmt.Errorf("getOsmPointFromLocationIQResponse error: %w", err)
+	}
+	boundary, err := parseBoundary(resp.GeoJSON)
+	if err != nil {
+		globalErr = fmt.Errorf("parseBoundary error: %w", err)
+	}
+	place := point.Place()
+	place.Boundary = boundary
+	if place.Kind == KindCity {
+		place.Address = resp.getCityAddress()
+	} else if place.Kind == KindStreet {
+		place.Address = resp.getStreetAddress()
+	}
+	return place, globalErr
+}
+
+// placeKind maps an OSM class and type to a Kind, falling back to the address components
+func placeKind(class, placeType string, a *Address) Kind {
+	switch class {
+	case "place":
+		switch placeType {
+		case "house":
+			return KindHouse
+		case "neighbourhood", "suburb", "quarter", "borough", "city_block", "isolated_dwelling":
+			return KindNeighborhood
+		case "city", "town", "village", "hamlet", "municipality":
+			return KindCity
+		case "county":
+			return KindCounty
+		case "state", "province", "region":
+			return KindState
+		case "country":
+			return KindCountry
+		case "postcode":
+			return KindPostcode
+		}
+	case "highway":
+		return KindStreet
+	case "building":
+		return KindHouse
+	case "boundary":
+		if placeType == "postal_code" {
+			return KindPostcode
+		}
+		if placeType == "administrative" {
+			return administrativeKind(a)
+		}
+	case "":
+	default:
+		return KindPOI
+	}
+	if a == nil {
+		return KindPOI
+	}
+	if a.HouseNumber != "" {
+		return KindHouse
+	}
+	if a.Street != "" {
+		return KindStreet
+	}
+	return administrativeKind(a)
+}
+
+// administrativeKind picks the most precise level present in an address without a street
+func administrativeKind(a *Address) Kind {
+	switch {
+	case a == nil:
+		return KindPOI
+	case a.Suburb != "", a.Neighbourhood != "":
+		// Neighbourhood also holds the quarter, like the "place" types above
+		return KindNeighborhood
+	case a.City != "":
+		return KindCity
+	case a.County != "":
+		return KindCounty
+	case a.State != "":
+		return KindState
+	case a.Country != "":
+		return KindCountry
+	case a.Postcode != "":
+		return KindPostcode
+	}
+	return KindPOI
+}
@@ -0,0 +1,107 @@
+package posm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlaceKind(t *testing.T) {
+	cases := []struct {
+		class, placeType string
+		address          *Address
+		want             Kind
+	}{
+		{"amenity", "cafe", &Address{HouseNumber: "66", Street: "Mint St"}, KindPOI},
+		{"place", "house", nil, KindHouse},
+		{"building", "yes", nil, KindHouse},
+		{"highway", "residential", nil, KindStreet},
+		{"place", "suburb", nil, KindNeighborhood},
+		{"place", "town", nil, KindCity},
+		{"place", "country", nil, KindCountry},
+		{"boundary", "postal_code", nil, KindPostcode},
+		{"boundary", "administrative", &Address{City: "Paris", State: "Ile-de-France", Country: "France"}, KindCity},
+		{"boundary", "administrative", &Address{State: "Bavaria", Country: "Germany"}, KindState},
+		{"", "", &Address{HouseNumber: "10", Street: "Market St", City: "San Francisco"}, KindHouse},
+		{"", "", &Address{Street: "Market St", City: "San Francisco"}, KindStreet},
+		{"", "", &Address{County: "Marin County"}, KindCounty},
+		{"", "", &Address{Neighbourhood: "Mission", City: "San Francisco"}, KindNeighborhood},
+		{"boundary", "administrative", &Address{Neighbourhood: "Nishi-Shinjuku", City: "Shinjuku", State: "Tokyo"}, KindNeighborhood},
+		{"", "", (&address{Quarter: "Nishi-Shinjuku 2-chome", CityDistrict: "Shinjuku"}).export(), KindNeighborhood},
+		{"", "", &Address{CityDistrict: "Shinjuku", City: "Shinjuku"}, KindCity},
+		{"", "", nil, KindPOI},
+	}
+	for _, c := range cases {
+		if got := placeKind(c.class, c.placeType, c.address); got != c.want {
+			t.Fatalf("placeKind(%q, %q, %+v) = %s, want %s", c.class, c.placeType, c.address, got, c.want)
+		}
+	}
+}
+
+func TestPlaceConversions(t *testing.T) {
+	city := &OsmCity{PlaceID: "R1", Lat: 1, Lng: 2, Address: "Paris", Timezone: &Timezone{Name: "Europe/Paris"}}
+	place := city.Place()
+	if place.Kind != KindCity || place.PlaceID != "R1" || place.Timezone == nil {
+		t.Fatalf("unexpected city place %+v", place)
+	}
+	if back := place.City(); back.PlaceID != "R1" || back.Lat != 1 || back.Timezone != city.Timezone {
+		t.Fatalf("unexpected city %+v", back)
+	}
+	point := &OsmPoint{PlaceID: "N1", Name: "Blue Bottle", Category: "amenity:cafe", Distance: 12}
+	if place := point.Place(); place.Kind != KindPOI || place.Name != "Blue Bottle" || place.Point().Distance != 12 {
+		t.Fatalf("unexpected point place %+v", place)
+	}
+	if place := (&OsmStreet{PlaceID: "W1"}).Place(); place.Kind != KindStreet || place.Street().PlaceID != "W1" {
+		t.Fatalf("unexpected street place %+v", place)
+	}
+	var nilCity *OsmCity
+	if nilCity.Place() != nil || (*Place)(nil).Point() != nil {
+		t.Fatalf("nil conversions should return nil")
+	}
+
+	failure := errors.New("failed")
+	if place, err := PlaceOf(nilCity, failure); place != nil || err != failure {
+		t.Fatalf("PlaceOf should pass through errors")
+	}
+	places, err := PlacesOf([]*OsmPoint{point}, nil)
+	if err != nil || len(places) != 1 || places[0].PlaceID != "N1" {
+		t.Fatalf("unexpected places %+v", places)
+	}
+	byID, _ := PlacesByIDOf(map[string]*OsmStreet{"W1": {PlaceID: "W1"}}, nil)
+	if byID["W1"].Kind != KindStreet {
+		t.Fatalf("unexpected places by id %+v", byID)
+	}
+}
+
+func TestGetPlaceBySearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"place_id":"1","osm_type":"relation","osm_id":"7444","class":"boundary","type":"administrative",
+			"display_name":"Paris, Ile-de-France, France","lat":"48.85","lon":"2.35",
+			"geojson":{"type":"Polygon","coordinates":[[[2.2,48.8],[2.4,48.8],[2.4,48.9],[2.2,48.8]]]},
+			"address":{"city":"Paris","state":"Ile-de-France","country":"France","country_code":"fr"}}]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	place, err := GetPlaceBySearch("Paris")
+	if err != nil {
+		t.Fatalf("GetPlaceBySearch error: %v", err)
+	}
+	if place.Kind != KindCity || place.PlaceID != "R7444" || place.Address != "Paris, Ile-de-France" || place.Lat != 48.85 {
+		t.Fatalf("unexpected place %+v", place)
+	}
+	if place.Boundary == nil || place.Boundary.Type != GeometryPolygon {
+		t.Fatalf("city places should carry their boundary, got %+v", place.Boundary)
+	}
+	if _, err := GetPlaceByCoordinates(-91, 0); err == nil {
+		t.Fatalf("invalid coordinates should be rejected")
+	}
+
+	// parse errors are returned together with the place, like the other converters
+	place, err = getPlaceFromLocationIQResponse(&locationIQResponse{OsmType: "node", OsmID: "1", Lat: "oops", Lng: "2"})
+	if err == nil || place == nil || place.PlaceID != "N1" {
+		t.Fatalf("expected the place with its error, got %+v %v", place, err)
+	}
+}
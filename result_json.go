@@ -0,0 +1,137 @@
+package posm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ResultSchemaVersion is written as schema_version by the JSON encoding of results.
+// Results without a version were encoded with Go field names and are still read.
+const ResultSchemaVersion = 1
+
+type versionedJSON struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+func (c OsmCity) MarshalJSON() ([]byte, error) {
+	type plain OsmCity
+	return json.Marshal(struct {
+		versionedJSON
+		plain
+	}{versionedJSON{ResultSchemaVersion}, plain(c)})
+}
+
+func (c *OsmCity) UnmarshalJSON(data []byte) error {
+	type plain OsmCity
+	if err := unmarshalVersioned(data, (*plain)(c)); err != nil {
+		return err
+	}
+	c.Timezone.loadLocation()
+	return nil
+}
+
+func (p OsmPoint) MarshalJSON() ([]byte, error) {
+	type plain OsmPoint
+	return json.Marshal(struct {
+		versionedJSON
+		plain
+	}{versionedJSON{ResultSchemaVersion}, plain(p)})
+}
+
+func (p *OsmPoint) UnmarshalJSON(data []byte) error {
+	type plain OsmPoint
+	return unmarshalVersioned(data, (*plain)(p))
+}
+
+func (s OsmStreet) MarshalJSON() ([]byte, error) {
+	type plain OsmStreet
+	return json.Marshal(struct {
+		versionedJSON
+		plain
+	}{versionedJSON{ResultSchemaVersion}, plain(s)})
+}
+
+func (s *OsmStreet) UnmarshalJSON(data []byte) error {
+	type plain OsmStreet
+	return unmarshalVersioned(data, (*plain)(s))
+}
+
+// unmarshalVersioned decodes a result, rewriting the keys of unversioned data first
+func unmarshalVersioned(data []byte, v any) error {
+	var version versionedJSON
+	if err := json.Unmarshal(data, &version); err != nil {
+		return err
+	}
+	switch {
+	case version.SchemaVersion > ResultSchemaVersion:
+		return fmt.Errorf("unsupported schema version %d", version.SchemaVersion)
+	case version.SchemaVersion == 0:
+		var legacy any
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
+		}
+		converted, err := json.Marshal(legacyJSON(legacy, reflect.TypeOf(v)))
+		if err != nil {
+			return err
+		}
+		data = converted
+	}
+	return json.Unmarshal(data, v)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// legacyJSON renames Go field name keys to the json tags of t, recursing into nested types.
+// Types with their own decoding and map keys are left untouched.
+func legacyJSON(value any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		switch {
+		case t.Kind() == reflect.Map:
+			for key, item := range value {
+				value[key] = legacyJSON(item, t.Elem())
+			}
+		case t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(jsonUnmarshalerType):
+			renamed := make(map[string]any, len(value))
+			for key, item := range value {
+				field, ok := t.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+				if !ok || !field.IsExported() {
+					renamed[key] = item
+					continue
+				}
+				name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+				if name == "-" {
+					continue
+				}
+				if name == "" {
+					name = field.Name
+				}
+				renamed[name] = legacyJSON(item, field.Type)
+			}
+			return renamed
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range value {
+				value[i] = legacyJSON(item, t.Elem())
+			}
+		}
+	}
+	return value
+}
+
+// loadLocation restores Location, which is not serialized, from the zone name
+func (tz *Timezone) loadLocation() {
+	if tz == nil || tz.Name == "" {
+		return
+	}
+	if location, err := time.LoadLocation(tz.Name); err == nil {
+		tz.Location = location
+	}
+}
@@ -0,0 +1,62 @@
+package posm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestResultJSON(t *testing.T) {
+	city := OsmCity{
+		PlaceID:           "R123",
+		Lat:               37.77,
+		Lng:               -122.42,
+		DisplayName:       "San Francisco, California, United States",
+		Address:           "San Francisco, California",
+		StructuredAddress: &Address{City: "San Francisco", State: "California", CountryCode: "us"},
+		BBox:              &BBox{MinLat: 37.6, MinLng: -122.5, MaxLat: 37.8, MaxLng: -122.3},
+		Details:           &PlaceDetails{Population: 808437, Names: map[string]string{"de": "San Francisco"}},
+		Timezone:          &Timezone{Name: "America/Los_Angeles", OffsetSeconds: -25200},
+	}
+	data, err := json.Marshal(city)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	for _, want := range []string{`"schema_version":1`, `"place_id":"R123"`, `"structured_address":{"city":"San Francisco"`, `"min_lat":37.6`, `"offset_seconds":-25200`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("encoded city %s is missing %s", data, want)
+		}
+	}
+	var decoded OsmCity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if decoded.PlaceID != "R123" || *decoded.BBox != *city.BBox || decoded.Details.Population != 808437 || decoded.StructuredAddress.State != "California" {
+		t.Fatalf("unexpected round trip %+v", decoded)
+	}
+	if decoded.Timezone.Location == nil || decoded.Timezone.Location.String() != "America/Los_Angeles" {
+		t.Fatalf("time zone location should be restored, got %+v", decoded.Timezone)
+	}
+
+	// results cached before schema versioning used Go field names
+	legacy := `{"PlaceID":"N1","Lat":1.5,"Lng":2.5,"DisplayName":"Cafe","Address":"1 Main St","StreetSearchText":"Main St",
+		"BBox":{"MinLat":1,"MinLng":2,"MaxLat":3,"MaxLng":4},"Details":{"WikidataID":"Q1","ExtraTags":{"OpeningHours":"24/7"}},"Name":"Cafe"}`
+	var point OsmPoint
+	if err := json.Unmarshal([]byte(legacy), &point); err != nil {
+		t.Fatalf("legacy Unmarshal error: %v", err)
+	}
+	if point.PlaceID != "N1" || point.Lat != 1.5 || point.StreetSearchText != "Main St" || point.BBox.MaxLng != 4 || point.Details.WikidataID != "Q1" || point.Name != "Cafe" {
+		t.Fatalf("unexpected legacy point %+v", point)
+	}
+	if point.Details.ExtraTags["OpeningHours"] != "24/7" {
+		t.Fatalf("map keys should not be renamed, got %v", point.Details.ExtraTags)
+	}
+
+	var streets []OsmStreet
+	if err := json.Unmarshal([]byte(`[{"schema_version":1,"place_id":"W1"},{"PlaceID":"W2"}]`), &streets); err != nil || streets[0].PlaceID != "W1" || streets[1].PlaceID != "W2" {
+		t.Fatalf("unexpected streets %+v, err %v", streets, err)
+	}
+	if err := json.Unmarshal([]byte(`{"schema_version":99}`), &OsmStreet{}); err == nil {
+		t.Fatalf("newer schema versions should be rejected")
+	}
+}
@@ -0,0 +1,216 @@
+package posm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Routing profiles
+const (
+	ProfileDriving = "driving"
+	ProfileWalking = "walking"
+	ProfileCycling = "cycling"
+)
+
+// ErrNoRoute is returned when the routing service finds no route between the places
+var ErrNoRoute = errors.New("no route found")
+
+var directionsClient *Client
+
+// Route is the best route between two places
+type Route struct {
+	// Distance in meters
+	Distance float64
+	Duration time.Duration
+	// Geometry is the full route line decoded from the provider's polyline
+	Geometry []Coordinate
+	Steps    []RouteStep
+}
+
+// RouteStep is one maneuver of a route
+type RouteStep struct {
+	Distance float64
+	Duration time.Duration
+	// Name of the road travelled on during the step
+	Name string
+	// Maneuver and Modifier follow OSRM, e.g. "turn" and "left"
+	Maneuver string
+	Modifier string
+	Location Coordinate
+}
+
+type osrmRouteResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Distance float64 `json:"distance"`
+		Duration float64 `json:"duration"`
+		Geometry string  `json:"geometry"`
+		Legs     []struct {
+			Steps []struct {
+				Distance float64 `json:"distance"`
+				Duration float64 `json:"duration"`
+				Name     string  `json:"name"`
+				Maneuver struct {
+					Type     string     `json:"type"`
+					Modifier string     `json:"modifier"`
+					Location [2]float64 `json:"location"`
+				} `json:"maneuver"`
+			} `json:"steps"`
+		} `json:"legs"`
+	} `json:"routes"`
+}
+
+func GetRoute(from, to Coordinate, profile string) (*Route, error) {
+	return GetRouteContext(context.Background(), from, to, profile)
+}
+
+// GetRouteContext is like GetRoute but uses ctx for the upstream request
+func GetRouteContext(ctx context.Context, from, to Coordinate, profile string) (*Route, error) {
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+	for _, c := range []Coordinate{from, to} {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+	result, err := directions(ctx, profile, []Coordinate{from, to})
+	if err != nil {
+		return nil, fmt.Errorf("directions error: %w", err)
+	}
+	if len(result.Routes) == 0 {
+		return nil, ErrNoRoute
+	}
+	best := result.Routes[0]
+	geometry, err := decodePolyline(best.Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("decodePolyline error: %w", err)
+	}
+	route := &Route{
+		Distance: best.Distance,
+		Duration: seconds(best.Duration),
+		Geometry: geometry,
+	}
+	for _, leg := range best.Legs {
+		for _, step := range leg.Steps {
+			route.Steps = append(route.Steps, RouteStep{
+				Distance: step.Distance,
+				Duration: seconds(step.Duration),
+				Name:     step.Name,
+				Maneuver: step.Maneuver.Type,
+				Modifier: step.Maneuver.Modifier,
+				Location: Coordinate{Lat: step.Maneuver.Location[1], Lng: step.Maneuver.Location[0]},
+			})
+		}
+	}
+	return route, nil
+}
+
+// directions requests a route through the coordinates from LocationIQ or an OSRM server
+func directions(ctx context.Context, profile string, coordinates []Coordinate) (*osrmRouteResponse, error) {
+	params := url.Values{}
+	if !directionsClient.keyless {
+		params.Set("key", locationIQAccessToken)
+	}
+	params.Set("steps", "true")
+	params.Set("overview", "full")
+	params.Set("geometries", "polyline")
+	path := "/" + profile + "/" + osrmCoordinates(coordinates)
+	resp, err := directionsClient.getPath(ctx, EndpointDirections, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result osrmRouteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := osrmError(result.Code, result.Message); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	return &result, nil
+}
+
+// osrmError converts an OSRM response code into an error, nil for "Ok"
+func osrmError(code, message string) error {
+	switch code {
+	case "Ok", "":
+		return nil
+	case "NoRoute", "NoTable":
+		return ErrNoRoute
+	default:
+		return fmt.Errorf("routing error %s: %s", code, message)
+	}
+}
+
+// osrmCoordinates formats coordinates as OSRM's "lng,lat;lng,lat"
+func osrmCoordinates(coordinates []Coordinate) string {
+	parts := make([]string, len(coordinates))
+	for i, c := range coordinates {
+		parts[i] = strconv.FormatFloat(c.Lng, 'f', -1, 64) + "," + strconv.FormatFloat(c.Lat, 'f', -1, 64)
+	}
+	return strings.Join(parts, ";")
+}
+
+func validateProfile(profile string) error {
+	switch profile {
+	case ProfileDriving, ProfileWalking, ProfileCycling:
+		return nil
+	}
+	return fmt.Errorf("invalid routing profile %q", profile)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// decodePolyline decodes a Google encoded polyline with 5 digits of precision
+func decodePolyline(encoded string) ([]Coordinate, error) {
+	coordinates := make([]Coordinate, 0, len(encoded)/4)
+	var lat, lng int
+	for i := 0; i < len(encoded); {
+		var deltas [2]int
+		for j := range deltas {
+			result, shift := 0, 0
+			for {
+				if i >= len(encoded) {
+					return nil, fmt.Errorf("truncated polyline")
+				}
+				b := int(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, fmt.Errorf("invalid polyline character %q", encoded[i-1])
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[j] = ^(result >> 1)
+			} else {
+				deltas[j] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		coordinates = append(coordinates, Coordinate{Lat: float64(lat) / 1e5, Lng: float64(lng) / 1e5})
+	}
+	return coordinates, nil
+}
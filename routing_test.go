@@ -0,0 +1,78 @@
+package posm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	coordinates, err := decodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@")
+	if err != nil {
+		t.Fatalf("decodePolyline error: %v", err)
+	}
+	want := []Coordinate{{Lat: 38.5, Lng: -120.2}, {Lat: 40.7, Lng: -120.95}, {Lat: 43.252, Lng: -126.453}}
+	if len(coordinates) != len(want) {
+		t.Fatalf("decodePolyline = %v", coordinates)
+	}
+	for i := range want {
+		if coordinates[i] != want[i] {
+			t.Fatalf("decodePolyline[%d] = %v, want %v", i, coordinates[i], want[i])
+		}
+	}
+	if _, err := decodePolyline("_p~iF~ps|"); err == nil {
+		t.Fatalf("truncated polylines should be rejected")
+	}
+}
+
+func TestGetRoute(t *testing.T) {
+	var gotPath, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotKey = r.URL.Path, r.URL.Query().Get("key")
+		if strings.Contains(r.URL.Path, "0,0") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"code":"NoRoute","message":"Impossible route between points"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"code":"Ok","routes":[{"distance":1520.4,"duration":300.5,"geometry":"_p~iF~ps|U_ulLnnqC","legs":[{"steps":[
+			{"distance":1000,"duration":200,"name":"Market Street","maneuver":{"type":"depart","location":[-122.4,37.79]}},
+			{"distance":520.4,"duration":100.5,"name":"Howard Street","maneuver":{"type":"turn","modifier":"left","location":[-122.39,37.78]}}
+		]}]}]}`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	from := (&OsmPoint{Lat: 37.79, Lng: -122.4}).Coordinate()
+	to := (&OsmCity{Lat: 37.78, Lng: -122.39}).Coordinate()
+	route, err := GetRoute(from, to, ProfileWalking)
+	if err != nil {
+		t.Fatalf("GetRoute error: %v", err)
+	}
+	if gotPath != "/directions/walking/-122.4,37.79;-122.39,37.78" || gotKey != "test-key" {
+		t.Fatalf("unexpected request path=%s key=%s", gotPath, gotKey)
+	}
+	if route.Distance != 1520.4 || route.Duration != 300500*time.Millisecond || len(route.Geometry) != 2 || len(route.Steps) != 2 {
+		t.Fatalf("unexpected route: %+v", route)
+	}
+	if step := route.Steps[1]; step.Name != "Howard Street" || step.Maneuver != "turn" || step.Modifier != "left" || step.Location != (Coordinate{Lat: 37.78, Lng: -122.39}) {
+		t.Fatalf("unexpected step: %+v", step)
+	}
+
+	if _, err := GetRoute(Coordinate{}, to, ProfileDriving); !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("NoRoute should map to ErrNoRoute, got %v", err)
+	}
+	if _, err := GetRoute(from, to, "flying"); err == nil {
+		t.Fatalf("unknown profiles should be rejected")
+	}
+
+	// a self-hosted OSRM server never receives the LocationIQ key
+	Init("secret", WithRoutingURL(server.URL+"/route/v1/"))
+	defer setupClientsForServer(server)
+	if _, err := GetRoute(from, to, ProfileDriving); err != nil || gotPath != "/route/v1/driving/-122.4,37.79;-122.39,37.78" || gotKey != "" {
+		t.Fatalf("OSRM request path=%s key=%q err=%v", gotPath, gotKey, err)
+	}
+}
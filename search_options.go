@@ -0,0 +1,272 @@
+package posm
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSearchLimit is the largest number of results LocationIQ returns per request
+const maxSearchLimit = 50
+
+// defaultProximityWeight favours distance over upstream order
+const defaultProximityWeight = 0.7
+
+// defaultAutocompleteLimit is used by autocomplete when no limit is given
+const defaultAutocompleteLimit = 10
+
+// SearchOptions narrows and shapes search and autocomplete results
+type SearchOptions struct {
+	CountryCodes  []string
+	Viewbox       *BBox
+	Bounded       bool
+	Languages     []string
+	Limit         int
+	Dedupe        *bool
+	NormalizeCity bool
+	// Proximity re-ranks results by a blend of upstream order and distance to this point
+	Proximity *Coordinate
+	// ProximityWeight is the share of distance in the blend, from 0 to 1, defaults to 0.7
+	ProximityWeight float64
+	// Timezone attaches the time zone to city results, costing one cached lookup per city
+	Timezone bool
+
+	// boundary requests city boundary polygons, set internally by city functions
+	boundary bool
+}
+
+// SearchOption configures a single search or autocomplete call
+type SearchOption func(*SearchOptions)
+
+// WithSearchOptions replaces all options with o
+func WithSearchOptions(o SearchOptions) SearchOption {
+	return func(options *SearchOptions) {
+		*options = o
+	}
+}
+
+// WithCountryCodes restricts results to the given ISO 3166-1 alpha-2 countries
+func WithCountryCodes(codes ...string) SearchOption {
+	return func(o *SearchOptions) {
+		o.CountryCodes = append(o.CountryCodes, codes...)
+	}
+}
+
+// WithViewbox prefers results inside box, or only returns those when bounded is set
+func WithViewbox(box BBox, bounded bool) SearchOption {
+	return func(o *SearchOptions) {
+		o.Viewbox = &box
+		o.Bounded = bounded
+	}
+}
+
+// WithLanguage requests names in the given languages, most preferred first
+func WithLanguage(languages ...string) SearchOption {
+	return func(o *SearchOptions) {
+		o.Languages = append(o.Languages, languages...)
+	}
+}
+
+// WithLimit caps the number of upstream results
+func WithLimit(limit int) SearchOption {
+	return func(o *SearchOptions) {
+		o.Limit = limit
+	}
+}
+
+// WithDedupe turns upstream deduplication of results on or off
+func WithDedupe(dedupe bool) SearchOption {
+	return func(o *SearchOptions) {
+		o.Dedupe = &dedupe
+	}
+}
+
+// WithNormalizeCity fills the city field from other address levels when it is missing
+func WithNormalizeCity() SearchOption {
+	return func(o *SearchOptions) {
+		o.NormalizeCity = true
+	}
+}
+
+// WithProximity ranks results near the reference point higher
+func WithProximity(lat, lng float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.Proximity = &Coordinate{Lat: lat, Lng: lng}
+	}
+}
+
+// WithHomeProximity ranks results near the home location set by WithHomeLocation higher
+func WithHomeProximity() SearchOption {
+	return func(o *SearchOptions) {
+		home := homeLocation
+		o.Proximity = &home
+	}
+}
+
+// WithProximityWeight sets how much distance counts against upstream order when re-ranking
+func WithProximityWeight(weight float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.ProximityWeight = weight
+	}
+}
+
+// WithTimezone attaches the time zone to every city result
+func WithTimezone() SearchOption {
+	return func(o *SearchOptions) {
+		o.Timezone = true
+	}
+}
+
+func newSearchOptions(opts []SearchOption) (*SearchOptions, error) {
+	o := &SearchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid search options: %w", err)
+	}
+	return o, nil
+}
+
+// Validate checks the options before any request is sent
+func (o *SearchOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, code := range o.CountryCodes {
+		if !isCountryCode(code) {
+			return fmt.Errorf("invalid country code %q", code)
+		}
+	}
+	if o.Viewbox != nil {
+		if err := o.Viewbox.validate(); err != nil {
+			return fmt.Errorf("invalid viewbox: %w", err)
+		}
+	} else if o.Bounded {
+		return fmt.Errorf("bounded requires a viewbox")
+	}
+	for _, language := range o.Languages {
+		if strings.TrimSpace(language) == "" || strings.ContainsAny(language, ", ") {
+			return fmt.Errorf("invalid language %q", language)
+		}
+	}
+	if o.Proximity != nil && (o.Proximity.Lat < -90 || o.Proximity.Lat > 90 || o.Proximity.Lng < -180 || o.Proximity.Lng > 180) {
+		return fmt.Errorf("invalid proximity %+v", *o.Proximity)
+	}
+	if o.ProximityWeight < 0 || o.ProximityWeight > 1 {
+		return fmt.Errorf("invalid proximity weight %v: must be between 0 and 1", o.ProximityWeight)
+	}
+	if o.Limit < 0 || o.Limit > maxSearchLimit {
+		return fmt.Errorf("invalid limit %d: must be between 1 and %d", o.Limit, maxSearchLimit)
+	}
+	return nil
+}
+
+// apply adds the options to the request parameters
+func (o *SearchOptions) apply(params url.Values) {
+	if o == nil {
+		return
+	}
+	if len(o.CountryCodes) > 0 {
+		codes := make([]string, len(o.CountryCodes))
+		for i, code := range o.CountryCodes {
+			codes[i] = strings.ToLower(code)
+		}
+		params.Set("countrycodes", strings.Join(codes, ","))
+	}
+	if o.Viewbox != nil {
+		params.Set("viewbox", o.Viewbox.viewbox())
+		if o.Bounded {
+			params.Set("bounded", "1")
+		}
+	}
+	if len(o.Languages) > 0 {
+		params.Set("accept-language", strings.Join(o.Languages, ","))
+	}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Dedupe != nil {
+		params.Set("dedupe", boolParam(*o.Dedupe))
+	}
+	if o.NormalizeCity {
+		params.Set("normalizecity", "1")
+	}
+}
+
+// applyDetails adds the parameters that shape each result, shared by search, lookup and reverse
+func (o *SearchOptions) applyDetails(params url.Values) {
+	params.Set("extratags", "1")
+	params.Set("namedetails", "1")
+	if o != nil && o.boundary {
+		params.Set("polygon_geojson", "1")
+	}
+}
+
+// rank re-orders results by proximity when a reference point is set
+func (o *SearchOptions) rank(results []locationIQResponse) []locationIQResponse {
+	if o == nil || o.Proximity == nil || len(results) < 2 {
+		return results
+	}
+	weight := o.ProximityWeight
+	if weight == 0 {
+		weight = defaultProximityWeight
+	}
+	distances := make([]float64, len(results))
+	maxDistance := 0.0
+	for i := range results {
+		lat, lng, err := results[i].parseCoordinates()
+		if err != nil {
+			distances[i] = -1
+			continue
+		}
+		distances[i] = distanceMeters(*o.Proximity, Coordinate{Lat: lat, Lng: lng})
+		if distances[i] > maxDistance {
+			maxDistance = distances[i]
+		}
+	}
+	scores := make([]float64, len(results))
+	for i := range results {
+		normalizedDistance := 1.0
+		if distances[i] >= 0 && maxDistance > 0 {
+			normalizedDistance = distances[i] / maxDistance
+		} else if distances[i] >= 0 {
+			normalizedDistance = 0
+		}
+		normalizedRank := float64(i) / float64(len(results)-1)
+		scores[i] = (1-weight)*normalizedRank + weight*normalizedDistance
+	}
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] < scores[order[b]]
+	})
+	ranked := make([]locationIQResponse, len(results))
+	for i, index := range order {
+		ranked[i] = results[index]
+	}
+	return ranked
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range strings.ToLower(code) {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
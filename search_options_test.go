@@ -0,0 +1,108 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSearchOptionsParams(t *testing.T) {
+	var got url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		_, _ = fmt.Fprint(w, `[ {"place_id":"1","display_name":"City","lat":"1","lon":"2","address":{"city":"San Jose","state":"CA"}} ]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	_, err := GetCitiesBySearch("san",
+		WithCountryCodes("US", "ca"),
+		WithViewbox(BBox{MinLat: 37, MinLng: -123, MaxLat: 38.5, MaxLng: -121.5}, true),
+		WithLanguage("de", "en"),
+		WithLimit(5),
+		WithDedupe(false),
+		WithNormalizeCity(),
+	)
+	if err != nil {
+		t.Fatalf("GetCitiesBySearch failed: %v", err)
+	}
+	want := map[string]string{
+		"countrycodes":    "us,ca",
+		"viewbox":         "-123,37,-121.5,38.5",
+		"bounded":         "1",
+		"accept-language": "de,en",
+		"limit":           "5",
+		"dedupe":          "0",
+		"normalizecity":   "1",
+	}
+	for key, value := range want {
+		if got.Get(key) != value {
+			t.Fatalf("param %s = %q, want %q", key, got.Get(key), value)
+		}
+	}
+
+	if _, err := GetCitiesByAutocomplete("san"); err != nil {
+		t.Fatalf("GetCitiesByAutocomplete failed: %v", err)
+	}
+	if got.Get("limit") != "10" || got.Get("dedupe") != "1" {
+		t.Fatalf("autocomplete defaults not sent: %v", got)
+	}
+	if _, err := GetCitiesByAutocomplete("san", WithSearchOptions(SearchOptions{Limit: 3})); err != nil {
+		t.Fatalf("GetCitiesByAutocomplete failed: %v", err)
+	}
+	if got.Get("limit") != "3" {
+		t.Fatalf("autocomplete limit not overridden: %v", got)
+	}
+}
+
+func TestSearchOptionsValidate(t *testing.T) {
+	invalid := [][]SearchOption{
+		{WithCountryCodes("usa")},
+		{WithViewbox(BBox{MinLat: 38, MinLng: -123, MaxLat: 37, MaxLng: -121}, false)},
+		{WithSearchOptions(SearchOptions{Bounded: true})},
+		{WithLanguage("en,de")},
+		{WithLimit(51)},
+		{WithLimit(-1)},
+	}
+	for i, opts := range invalid {
+		if _, err := GetPointsBySearch("san", opts...); err == nil {
+			t.Fatalf("case %d: invalid options should be rejected before any request", i)
+		}
+	}
+}
+
+func TestProximityRanking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"place_id":"1","display_name":"Springfield, IL","lat":"39.80","lon":"-89.64","address":{"city":"Springfield","state":"Illinois","country_code":"us"}},
+			{"place_id":"2","display_name":"Springfield, MO","lat":"37.21","lon":"-93.29","address":{"city":"Springfield","state":"Missouri","country_code":"us"}},
+			{"place_id":"3","display_name":"Springfield, MA","lat":"42.10","lon":"-72.59","address":{"city":"Springfield","state":"Massachusetts","country_code":"us"}}
+		]`)
+	}))
+	defer server.Close()
+
+	Init("key", WithHomeLocation(42.36, -71.06))
+	setupClientsForServer(server)
+	defer func() { homeLocation = Coordinate{Lat: HEADQUARTER_LAT, Lng: HEADQUARTER_LNG} }()
+
+	cities, err := GetCitiesByAutocomplete("spring")
+	if err != nil || len(cities) != 3 || cities[0].DisplayName != "Springfield, IL" {
+		t.Fatalf("without proximity upstream order should be kept: %v %v", cities, err)
+	}
+
+	cities, err = GetCitiesByAutocomplete("spring", WithHomeProximity())
+	if err != nil || len(cities) != 3 || cities[0].DisplayName != "Springfield, MA" {
+		t.Fatalf("home proximity should rank Springfield, MA first: %+v %v", cities[0], err)
+	}
+
+	city, err := GetCityBySearch("spring", WithProximity(37.2, -93.3), WithProximityWeight(0.9))
+	if err != nil || city.DisplayName != "Springfield, MO" {
+		t.Fatalf("proximity should pick Springfield, MO: %+v %v", city, err)
+	}
+
+	if _, err := GetCityBySearch("spring", WithProximityWeight(2)); err == nil {
+		t.Fatalf("out of range proximity weight should be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+package posm
+
+import (
+	"strings"
+)
+
+// StateNames controls how state and province names appear in formatted addresses
+type StateNames uint8
+
+const (
+	// StateNamesAsReturned keeps the names LocationIQ returns
+	StateNamesAsReturned StateNames = iota
+	// StateNamesAbbreviated uses postal abbreviations such as "CA" where they are known
+	StateNamesAbbreviated
+	// StateNamesFull uses full names such as "California" where they are known
+	StateNamesFull
+)
+
+// stateNames is set by Init with WithStateNames
+var stateNames = StateNamesAsReturned
+
+// stateAbbreviations maps lower-case country codes to full state names and their abbreviations
+var stateAbbreviations = map[string]map[string]string{
+	"us": {
+		"Alabama": "AL", "Alaska": "AK", "Arizona": "AZ", "Arkansas": "AR", "California": "CA",
+		"Colorado": "CO", "Connecticut": "CT", "Delaware": "DE", "Florida": "FL", "Georgia": "GA",
+		"Hawaii": "HI", "Idaho": "ID", "Illinois": "IL", "Indiana": "IN", "Iowa": "IA",
+		"Kansas": "KS", "Kentucky": "KY", "Louisiana": "LA", "Maine": "ME", "Maryland": "MD",
+		"Massachusetts": "MA", "Michigan": "MI", "Minnesota": "MN", "Mississippi": "MS", "Missouri": "MO",
+		"Montana": "MT", "Nebraska": "NE", "Nevada": "NV", "New Hampshire": "NH", "New Jersey": "NJ",
+		"New Mexico": "NM", "New York": "NY", "North Carolina": "NC", "North Dakota": "ND", "Ohio": "OH",
+		"Oklahoma": "OK", "Oregon": "OR", "Pennsylvania": "PA", "Rhode Island": "RI", "South Carolina": "SC",
+		"South Dakota": "SD", "Tennessee": "TN", "Texas": "TX", "Utah": "UT", "Vermont": "VT",
+		"Virginia": "VA", "Washington": "WA", "West Virginia": "WV", "Wisconsin": "WI", "Wyoming": "WY",
+		"District of Columbia": "DC", "Puerto Rico": "PR", "Guam": "GU", "American Samoa": "AS",
+		"United States Virgin Islands": "VI", "Northern Mariana Islands": "MP",
+	},
+	"ca": {
+		"Alberta": "AB", "British Columbia": "BC", "Manitoba": "MB", "New Brunswick": "NB",
+		"Newfoundland and Labrador": "NL", "Nova Scotia": "NS", "Ontario": "ON", "Prince Edward Island": "PE",
+		"Quebec": "QC", "Québec": "QC", "Saskatchewan": "SK", "Northwest Territories": "NT", "Nunavut": "NU", "Yukon": "YT",
+	},
+	"au": {
+		"New South Wales": "NSW", "Victoria": "VIC", "Queensland": "QLD", "South Australia": "SA",
+		"Western Australia": "WA", "Tasmania": "TAS", "Northern Territory": "NT", "Australian Capital Territory": "ACT",
+	},
+}
+
+// stateCountries is the lookup order when the country is unknown
+var stateCountries = []string{"us", "ca", "au"}
+
+// stateFullNames is the reverse of stateAbbreviations, preferring unaccented spellings
+var stateFullNames = func() map[string]map[string]string {
+	full := make(map[string]map[string]string, len(stateAbbreviations))
+	for country, states := range stateAbbreviations {
+		full[country] = make(map[string]string, len(states))
+		for name, abbreviation := range states {
+			if existing, ok := full[country][abbreviation]; ok && isASCII(existing) {
+				continue
+			}
+			full[country][abbreviation] = name
+		}
+	}
+	return full
+}()
+
+// AbbreviateState returns the postal abbreviation of a US state, Canadian province or Australian state,
+// or state unchanged when it is unknown. An empty country code tries the US, Canada and Australia in turn.
+func AbbreviateState(countryCode, state string) string {
+	if abbreviation, _, ok := lookupState(countryCode, state); ok {
+		return abbreviation
+	}
+	return state
+}
+
+// ExpandState returns the full name of a state abbreviation, or state unchanged when it is unknown
+func ExpandState(countryCode, state string) string {
+	if _, name, ok := lookupState(countryCode, state); ok {
+		return name
+	}
+	return state
+}
+
+// lookupState matches a full name or an abbreviation, ignoring case
+func lookupState(countryCode, state string) (string, string, bool) {
+	state = strings.TrimSpace(state)
+	if state == "" {
+		return "", "", false
+	}
+	countries := stateCountries
+	if countryCode != "" {
+		countries = []string{strings.ToLower(countryCode)}
+	}
+	for _, country := range countries {
+		for abbreviation, name := range stateFullNames[country] {
+			if strings.EqualFold(state, abbreviation) {
+				return abbreviation, name, true
+			}
+		}
+		for name, abbreviation := range stateAbbreviations[country] {
+			if strings.EqualFold(state, name) {
+				return abbreviation, stateFullNames[country][abbreviation], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// formatState applies the StateNames option set by Init
+func formatState(countryCode, state string) string {
+	switch stateNames {
+	case StateNamesAbbreviated:
+		return AbbreviateState(countryCode, state)
+	case StateNamesFull:
+		return ExpandState(countryCode, state)
+	}
+	return state
+}
+
+// NormalizeAddress returns a comparison key for formatted addresses, so "San Francisco, California"
+// and "san francisco, CA" match. Every part after the first has known state names abbreviated, in any
+// country, so results are only compared this way when they have no structured address.
+func NormalizeAddress(address string) string {
+	parts := strings.Split(address, ",")
+	for i, part := range parts {
+		part = strings.Join(strings.Fields(part), " ")
+		if i > 0 {
+			part = AbbreviateState("", part)
+		}
+		parts[i] = strings.ToLower(part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
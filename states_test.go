@@ -0,0 +1,155 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStateAbbreviations(t *testing.T) {
+	cases := []struct {
+		country, state, abbreviated, full string
+	}{
+		{"us", "California", "CA", "California"},
+		{"US", "ca", "CA", "California"},
+		{"ca", "Québec", "QC", "Quebec"},
+		{"ca", "BC", "BC", "British Columbia"},
+		{"au", "Western Australia", "WA", "Western Australia"},
+		{"us", "WA", "WA", "Washington"},
+		{"", "new south wales", "NSW", "New South Wales"},
+		{"de", "Bayern", "Bayern", "Bayern"},
+		{"us", "Atlantis", "Atlantis", "Atlantis"},
+	}
+	for _, c := range cases {
+		if got := AbbreviateState(c.country, c.state); got != c.abbreviated {
+			t.Fatalf("AbbreviateState(%q, %q) = %q, want %q", c.country, c.state, got, c.abbreviated)
+		}
+		if got := ExpandState(c.country, c.state); got != c.full {
+			t.Fatalf("ExpandState(%q, %q) = %q, want %q", c.country, c.state, got, c.full)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	if NormalizeAddress("San Francisco, California") != NormalizeAddress(" san  francisco, CA") {
+		t.Fatalf("state spellings should normalize to the same key")
+	}
+	if got := NormalizeAddress("Washington, District of Columbia"); got != "washington, dc" {
+		t.Fatalf("the first part should not be treated as a state, got %q", got)
+	}
+	if NormalizeAddress("Portland, OR") == NormalizeAddress("Portland, ME") {
+		t.Fatalf("different states should not match")
+	}
+}
+
+func TestStateNamesOption(t *testing.T) {
+	defer func() { stateNames = StateNamesAsReturned }()
+	resp := &locationIQResponse{Address: &address{City: "San Francisco", State: "California", CountryCode: "us"}}
+
+	stateNames = StateNamesAbbreviated
+	if got := resp.getCityAddress(); got != "San Francisco, CA" {
+		t.Fatalf("abbreviated getCityAddress() = %q", got)
+	}
+	stateNames = StateNamesFull
+	resp.Address.State = "CA"
+	if got := resp.getCityAddress(); got != "San Francisco, California" {
+		t.Fatalf("full getCityAddress() = %q", got)
+	}
+	// the structured address keeps the state as returned, the option only applies to formatted strings
+	if got := resp.Address.export(); got.State != "CA" || got.StateCode != "US-CA" || got.Format() != "San Francisco, California" {
+		t.Fatalf("export() = %+v", got)
+	}
+	stateNames = StateNamesAsReturned
+	if got := resp.getCityAddress(); got != "San Francisco, CA" {
+		t.Fatalf("getCityAddress() = %q", got)
+	}
+}
+
+func TestAutocompleteDedupesStateSpellings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"place_id":"a1","display_name":"A","lat":"37.77","lon":"-122.41","address":{"city":"San Francisco","state":"California","country_code":"us"}},
+			{"place_id":"a2","display_name":"B","lat":"37.77","lon":"-122.41","address":{"city":"San Francisco","state":"CA","country_code":"us"}}
+		]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	cities, err := GetCitiesByAutocomplete("San Francisco")
+	if err != nil || len(cities) != 1 {
+		t.Fatalf("state spellings should be deduplicated: %+v %v", cities, err)
+	}
+}
+
+func TestPointDedupIgnoresStateSpelling(t *testing.T) {
+	defer func() { stateNames = StateNamesAsReturned }()
+	full := &locationIQResponse{Address: &address{HouseNumber: "10", Road: "Market St", City: "San Francisco", State: "CA", Postcode: "94105", CountryCode: "us"}}
+	stateNames = StateNamesFull
+	fullPoint := &OsmPoint{Address: full.getPointAddress(), StructuredAddress: full.Address.export()}
+	stateNames = StateNamesAsReturned
+	returned := &locationIQResponse{Address: &address{HouseNumber: "10", Road: "Market St", City: "San Francisco", State: "California", Postcode: "94105", CountryCode: "us"}}
+	returnedPoint := &OsmPoint{Address: returned.getPointAddress(), StructuredAddress: returned.Address.export()}
+	abbreviated := &OsmPoint{Address: "10 Market St, San Francisco, CA 94105", StructuredAddress: &Address{HouseNumber: "10", Street: "Market St", City: "San Francisco", State: "CA", Postcode: "94105", CountryCode: "us"}}
+
+	if fullPoint.Address != "10 Market St, San Francisco, California 94105" || abbreviated.Address == fullPoint.Address {
+		t.Fatalf("unexpected formatted addresses %q and %q", fullPoint.Address, abbreviated.Address)
+	}
+	if fullPoint.dedupKey() != returnedPoint.dedupKey() || fullPoint.dedupKey() != abbreviated.dedupKey() {
+		t.Fatalf("state spellings should share a key: %q, %q, %q", fullPoint.dedupKey(), returnedPoint.dedupKey(), abbreviated.dedupKey())
+	}
+	other := &OsmPoint{StructuredAddress: &Address{HouseNumber: "10", Street: "Market St", City: "Portland", State: "OR", CountryCode: "us"}}
+	if other.dedupKey() == (&OsmPoint{StructuredAddress: &Address{HouseNumber: "10", Street: "Market St", City: "Portland", State: "ME", CountryCode: "us"}}).dedupKey() {
+		t.Fatalf("different states should not match")
+	}
+}
+
+func TestSearchDedupesStateSpellings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"place_id":"p1","osm_type":"node","osm_id":"1","display_name":"A","lat":"37.79","lon":"-122.39","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"California","postcode":"94105","country_code":"us"}},
+			{"place_id":"p2","osm_type":"node","osm_id":"2","display_name":"B","lat":"37.79","lon":"-122.39","address":{"house_number":"10","road":"Market St","city":"San Francisco","state":"CA","postcode":"94105","country_code":"us"}},
+			{"place_id":"s1","osm_type":"way","osm_id":"3","display_name":"C","lat":"37.79","lon":"-122.39","address":{"road":"Market St","city":"San Francisco","state":"California","country_code":"us"}},
+			{"place_id":"s2","osm_type":"way","osm_id":"4","display_name":"D","lat":"37.79","lon":"-122.39","address":{"road":"Market St","city":"San Francisco","state":"CA","country_code":"us"}}
+		]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	points, err := GetPointsBySearch("10 Market St")
+	if err != nil || len(points) != 1 || points[0].Address != "10 Market St, San Francisco, California 94105" {
+		t.Fatalf("formatted point addresses should be deduplicated across state spellings: %+v %v", points, err)
+	}
+	streets, err := GetStreetsByAutocomplete("Market St")
+	if err != nil || len(streets) != 1 {
+		t.Fatalf("street addresses should be deduplicated across state spellings: %+v %v", streets, err)
+	}
+}
+
+func TestDedupKeyKeepsCountryStates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"place_id":"c1","osm_type":"relation","osm_id":"1","display_name":"Tbilisi","lat":"41.69","lon":"44.80","address":{"city":"Tbilisi","state":"Georgia","country_code":"ge"}},
+			{"place_id":"c2","osm_type":"relation","osm_id":"2","display_name":"Tbilisi","lat":"33.75","lon":"-84.39","address":{"city":"Tbilisi","state":"GA","country_code":"us"}},
+			{"place_id":"c3","osm_type":"relation","osm_id":"3","display_name":"Tbilisi","lat":"33.75","lon":"-84.39","address":{"city":"Tbilisi","state":"Georgia","country_code":"us"}}
+		]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	// "Georgia" is only abbreviated to GA within the us, the Georgian capital stays a separate result
+	cities, err := GetCitiesByAutocomplete("Tbilisi")
+	if err != nil || len(cities) != 2 || cities[0].PlaceID != "R1" || cities[1].PlaceID != "R2" {
+		t.Fatalf("cities should be deduplicated per country: %+v %v", cities, err)
+	}
+
+	// the city and the state keep their own parts, unlike the comma-separated fallback
+	full := &OsmStreet{StructuredAddress: &Address{Street: "Broadway", City: "New York", State: "New York", CountryCode: "us"}}
+	short := &OsmStreet{StructuredAddress: &Address{Street: "Broadway", City: "NY", State: "NY", CountryCode: "us"}}
+	if full.dedupKey() == short.dedupKey() {
+		t.Fatalf("different cities should not match: %q", full.dedupKey())
+	}
+	if (&OsmCity{Address: "Tbilisi, Georgia"}).dedupKey() != NormalizeAddress("Tbilisi, Georgia") {
+		t.Fatalf("results without a structured address should use NormalizeAddress")
+	}
+}
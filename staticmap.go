@@ -0,0 +1,195 @@
+package posm
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Static map limits enforced by LocationIQ
+const (
+	MaxStaticMapSize = 1280
+	MaxStaticMapZoom = 18
+)
+
+// staticMapURL is the LocationIQ static map endpoint, the same for every region
+var staticMapURL = "https://maps.locationiq.com/v3/staticmap"
+
+// StaticMap builds a LocationIQ static map image URL.
+// Without a center the provider fits the map to the markers and paths.
+type StaticMap struct {
+	Width  int
+	Height int
+	Center *Coordinate
+	// Zoom is only sent together with Center
+	Zoom    int
+	Markers []StaticMapMarker
+	Paths   []StaticMapPath
+}
+
+// StaticMapMarker places an icon at a location, an empty Icon uses the provider default
+type StaticMapMarker struct {
+	Location Coordinate
+	Icon     string
+}
+
+// StaticMapPath draws a line, or a filled polygon when FillColor is set.
+// Colors are hex RGB like "ff0000", optionally with an alpha suffix.
+type StaticMapPath struct {
+	Coordinates []Coordinate
+	Weight      int
+	Color       string
+	FillColor   string
+}
+
+// NewStaticMap returns a map of the given size in pixels
+func NewStaticMap(width, height int) *StaticMap {
+	return &StaticMap{Width: width, Height: height}
+}
+
+// WithCenter centers the map on c at the given zoom level
+func (m *StaticMap) WithCenter(c Coordinate, zoom int) *StaticMap {
+	m.Center = &c
+	m.Zoom = zoom
+	return m
+}
+
+// AddMarker adds a marker at c
+func (m *StaticMap) AddMarker(c Coordinate, icon string) *StaticMap {
+	m.Markers = append(m.Markers, StaticMapMarker{Location: c, Icon: icon})
+	return m
+}
+
+// AddPoint adds a marker at the point
+func (m *StaticMap) AddPoint(point *OsmPoint, icon string) *StaticMap {
+	return m.AddMarker(point.Coordinate(), icon)
+}
+
+// AddPath adds a line or polygon overlay
+func (m *StaticMap) AddPath(path StaticMapPath) *StaticMap {
+	m.Paths = append(m.Paths, path)
+	return m
+}
+
+// AddBoundary adds every outer ring of b as a polygon overlay styled like style.
+// Large boundaries should be simplified first to keep the URL short.
+func (m *StaticMap) AddBoundary(b *Boundary, style StaticMapPath) *StaticMap {
+	if b == nil {
+		return m
+	}
+	for _, polygon := range b.Polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		path := style
+		path.Coordinates = make([]Coordinate, len(polygon[0]))
+		for i, position := range polygon[0] {
+			path.Coordinates[i] = Coordinate{Lat: position[1], Lng: position[0]}
+		}
+		m.Paths = append(m.Paths, path)
+	}
+	return m
+}
+
+// Validate checks the map against the provider limits
+func (m *StaticMap) Validate() error {
+	if m.Width <= 0 || m.Width > MaxStaticMapSize || m.Height <= 0 || m.Height > MaxStaticMapSize {
+		return fmt.Errorf("invalid size %dx%d: must be between 1 and %d", m.Width, m.Height, MaxStaticMapSize)
+	}
+	if m.Center == nil && len(m.Markers) == 0 && len(m.Paths) == 0 {
+		return fmt.Errorf("static map needs a center, a marker or a path")
+	}
+	if m.Center != nil {
+		if err := m.Center.validate(); err != nil {
+			return err
+		}
+		if m.Zoom < 0 || m.Zoom > MaxStaticMapZoom {
+			return fmt.Errorf("invalid zoom %d: must be between 0 and %d", m.Zoom, MaxStaticMapZoom)
+		}
+	}
+	for _, marker := range m.Markers {
+		if err := marker.Location.validate(); err != nil {
+			return err
+		}
+		if strings.ContainsAny(marker.Icon, "|") {
+			return fmt.Errorf("invalid marker icon %q", marker.Icon)
+		}
+	}
+	for _, path := range m.Paths {
+		if len(path.Coordinates) < 2 {
+			return fmt.Errorf("path needs at least two coordinates")
+		}
+		for _, c := range path.Coordinates {
+			if err := c.validate(); err != nil {
+				return err
+			}
+		}
+		if path.Weight < 0 {
+			return fmt.Errorf("invalid path weight %d", path.Weight)
+		}
+		for _, color := range []string{path.Color, path.FillColor} {
+			if !isHexColor(color) {
+				return fmt.Errorf("invalid color %q", color)
+			}
+		}
+	}
+	return nil
+}
+
+// URL returns the image URL signed with the key passed to Init
+func (m *StaticMap) URL() (string, error) {
+	if err := m.Validate(); err != nil {
+		return "", fmt.Errorf("invalid static map: %w", err)
+	}
+	if locationIQAccessToken == "" {
+		return "", fmt.Errorf("static maps need a LocationIQ key, call Init first")
+	}
+	params := url.Values{}
+	params.Set("key", locationIQAccessToken)
+	params.Set("size", fmt.Sprintf("%dx%d", m.Width, m.Height))
+	if m.Center != nil {
+		params.Set("center", staticMapCoordinate(*m.Center))
+		params.Set("zoom", strconv.Itoa(m.Zoom))
+	}
+	for _, marker := range m.Markers {
+		value := staticMapCoordinate(marker.Location)
+		if marker.Icon != "" {
+			value = "icon:" + marker.Icon + "|" + value
+		}
+		params.Add("markers", value)
+	}
+	for _, path := range m.Paths {
+		parts := []string{}
+		if path.Weight > 0 {
+			parts = append(parts, "weight:"+strconv.Itoa(path.Weight))
+		}
+		if path.Color != "" {
+			parts = append(parts, "color:"+path.Color)
+		}
+		if path.FillColor != "" {
+			parts = append(parts, "fillcolor:"+path.FillColor)
+		}
+		for _, c := range path.Coordinates {
+			parts = append(parts, staticMapCoordinate(c))
+		}
+		params.Add("path", strings.Join(parts, "|"))
+	}
+	return staticMapURL + "?" + params.Encode(), nil
+}
+
+func staticMapCoordinate(c Coordinate) string {
+	return strconv.FormatFloat(c.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(c.Lng, 'f', -1, 64)
+}
+
+// isHexColor accepts an empty string, RRGGBB or RRGGBBAA
+func isHexColor(color string) bool {
+	if color == "" {
+		return true
+	}
+	if len(color) != 6 && len(color) != 8 {
+		return false
+	}
+	_, err := strconv.ParseUint(color, 16, 32)
+	return err == nil
+}
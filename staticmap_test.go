@@ -0,0 +1,55 @@
+package posm
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStaticMapURL(t *testing.T) {
+	locationIQAccessToken = "test-key"
+	boundary := &Boundary{Type: GeometryPolygon, Polygons: [][][][2]float64{{{{-122.5, 37.7}, {-122.3, 37.7}, {-122.3, 37.8}, {-122.5, 37.7}}}}}
+	m := NewStaticMap(600, 400).
+		WithCenter(Coordinate{Lat: 37.75, Lng: -122.4}, 12).
+		AddPoint(&OsmPoint{Lat: 37.79, Lng: -122.4}, "large-red-cutout").
+		AddBoundary(boundary, StaticMapPath{Weight: 2, Color: "0000ff", FillColor: "0000ff33"})
+	raw, err := m.URL()
+	if err != nil {
+		t.Fatalf("URL error: %v", err)
+	}
+	if !strings.HasPrefix(raw, staticMapURL+"?") {
+		t.Fatalf("unexpected URL %s", raw)
+	}
+	parsed, _ := url.Parse(raw)
+	query := parsed.Query()
+	if query.Get("key") != "test-key" || query.Get("size") != "600x400" || query.Get("center") != "37.75,-122.4" || query.Get("zoom") != "12" {
+		t.Fatalf("unexpected query %v", query)
+	}
+	if query.Get("markers") != "icon:large-red-cutout|37.79,-122.4" {
+		t.Fatalf("unexpected markers %q", query.Get("markers"))
+	}
+	if query.Get("path") != "weight:2|color:0000ff|fillcolor:0000ff33|37.7,-122.5|37.7,-122.3|37.8,-122.3|37.7,-122.5" {
+		t.Fatalf("unexpected path %q", query.Get("path"))
+	}
+
+	invalid := []*StaticMap{
+		NewStaticMap(1281, 400).WithCenter(Coordinate{}, 1),
+		NewStaticMap(600, 0).WithCenter(Coordinate{}, 1),
+		NewStaticMap(600, 400),
+		NewStaticMap(600, 400).WithCenter(Coordinate{}, 19),
+		NewStaticMap(600, 400).AddMarker(Coordinate{Lat: 91}, ""),
+		NewStaticMap(600, 400).AddPath(StaticMapPath{Coordinates: []Coordinate{{}}}),
+		NewStaticMap(600, 400).AddPath(StaticMapPath{Coordinates: []Coordinate{{}, {Lat: 1}}, Color: "blue"}),
+	}
+	for i, m := range invalid {
+		if _, err := m.URL(); err == nil {
+			t.Fatalf("static map %d should be rejected", i)
+		}
+	}
+
+	// without a center the provider fits the map to the overlays
+	raw, err = NewStaticMap(300, 300).AddMarker(Coordinate{Lat: 1, Lng: 2}, "").URL()
+	if err != nil || strings.Contains(raw, "center=") || !strings.Contains(raw, "markers=1%2C2") {
+		t.Fatalf("unexpected URL %s, err %v", raw, err)
+	}
+}
@@ -1,27 +1,148 @@
 package posm
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Coordinate is a latitude/longitude pair in degrees
+type Coordinate struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
 type OsmCity struct {
-	PlaceID     string
-	Lat         float64
-	Lng         float64
-	DisplayName string
-	Address     string
+	PlaceID     string  `json:"place_id"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	DisplayName string  `json:"display_name"`
+	Address     string  `json:"address"`
+	// StructuredAddress holds the components behind Address
+	StructuredAddress *Address      `json:"structured_address,omitempty"`
+	BBox              *BBox         `json:"bbox,omitempty"`
+	Details           *PlaceDetails `json:"details,omitempty"`
+	// Timezone is only set when requested with WithTimezone
+	Timezone *Timezone `json:"timezone,omitempty"`
+	// Boundary is nil when the city is mapped as a single node
+	Boundary *Boundary `json:"boundary,omitempty"`
 }
 
 type OsmPoint struct {
-	PlaceID          string
-	Lat              float64
-	Lng              float64
-	DisplayName      string
-	Address          string
-	StreetSearchText string
-	CitySearchText   string
+	PlaceID     string  `json:"place_id"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	DisplayName string  `json:"display_name"`
+	Address     string  `json:"address"`
+	// StructuredAddress holds the components behind Address
+	StructuredAddress *Address      `json:"structured_address,omitempty"`
+	StreetSearchText  string        `json:"street_search_text,omitempty"`
+	CitySearchText    string        `json:"city_search_text,omitempty"`
+	BBox              *BBox         `json:"bbox,omitempty"`
+	Details           *PlaceDetails `json:"details,omitempty"`
+	Name              string        `json:"name,omitempty"`
+	Category          string        `json:"category,omitempty"`
+	// Distance in meters from the reference point of a nearby search
+	Distance float64 `json:"distance,omitempty"`
 }
 
 type OsmStreet struct {
-	PlaceID     string
-	Lat         float64
-	Lng         float64
-	DisplayName string
-	Address     string
+	PlaceID     string  `json:"place_id"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	DisplayName string  `json:"display_name"`
+	Address     string  `json:"address"`
+	// StructuredAddress holds the components behind Address
+	StructuredAddress *Address      `json:"structured_address,omitempty"`
+	BBox              *BBox         `json:"bbox,omitempty"`
+	Details           *PlaceDetails `json:"details,omitempty"`
+}
+
+// StructuredQuery holds the address components sent to LocationIQ structured search
+type StructuredQuery struct {
+	Street     string
+	City       string
+	County     string
+	State      string
+	Country    string
+	PostalCode string
+}
+
+// String joins the non-empty components into a free-text query
+func (q StructuredQuery) String() string {
+	parts := make([]string, 0, 6)
+	for _, part := range []string{q.Street, q.City, q.County, q.State, q.PostalCode, q.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c Coordinate) validate() error {
+	if c.Lat < -90 || c.Lat > 90 || c.Lng < -180 || c.Lng > 180 {
+		return fmt.Errorf("invalid coordinates %v, %v", c.Lat, c.Lng)
+	}
+	return nil
+}
+
+// Coordinate returns the city's centroid
+func (c *OsmCity) Coordinate() Coordinate {
+	return Coordinate{Lat: c.Lat, Lng: c.Lng}
+}
+
+// Coordinate returns the point's location
+func (p *OsmPoint) Coordinate() Coordinate {
+	return Coordinate{Lat: p.Lat, Lng: p.Lng}
+}
+
+// matchesSearch reports whether the result starts with the leading word from searchLead: the house
+// number, or the start of the house name, street or name. The formatted address follows the country's
+// order, so it may not start with the queried part.
+func (p *OsmPoint) matchesSearch(lead string) bool {
+	if lead == "" {
+		return true
+	}
+	a := p.StructuredAddress
+	if a == nil {
+		return strings.HasPrefix(strings.ToLower(p.DisplayName), lead)
+	}
+	if strings.EqualFold(a.HouseNumber, lead) {
+		return true
+	}
+	for _, value := range []string{a.HouseName, a.Street, p.Name} {
+		if strings.HasPrefix(strings.ToLower(value), lead) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupKey compares points by address components, see Address.dedupKey
+func (p *OsmPoint) dedupKey() string {
+	if a := p.StructuredAddress; a != nil {
+		return a.dedupKey(a.HouseName, a.HouseNumber, a.Street, a.City, a.Postcode)
+	}
+	return NormalizeAddress(p.Address)
+}
+
+// dedupKey compares cities by address components, see Address.dedupKey
+func (c *OsmCity) dedupKey() string {
+	if a := c.StructuredAddress; a != nil {
+		// getCityAddress falls back to the county the same way
+		return a.dedupKey(firstNonEmpty(a.City, a.County))
+	}
+	return NormalizeAddress(c.Address)
+}
+
+// dedupKey compares streets by address components, see Address.dedupKey
+func (s *OsmStreet) dedupKey() string {
+	if a := s.StructuredAddress; a != nil {
+		return a.dedupKey(a.Street, a.City)
+	}
+	return NormalizeAddress(s.Address)
+}
+
+// Coordinate returns the street's representative location
+func (s *OsmStreet) Coordinate() Coordinate {
+	return Coordinate{Lat: s.Lat, Lng: s.Lng}
 }
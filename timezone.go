@@ -0,0 +1,119 @@
+package posm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timezoneCacheSize bounds the cached zones, the cache is cleared when it fills up
+const timezoneCacheSize = 10000
+
+// Timezone is the IANA time zone of a place with its current offset
+type Timezone struct {
+	Name          string         `json:"name"`
+	ShortName     string         `json:"short_name,omitempty"`
+	OffsetSeconds int            `json:"offset_seconds"`
+	Location      *time.Location `json:"-"`
+}
+
+type timezoneResponse struct {
+	Timezone struct {
+		Name string `json:"name"`
+	} `json:"timezone"`
+}
+
+var timezoneClient *Client
+
+var timezoneCache = struct {
+	sync.Mutex
+	zones map[string]*time.Location
+}{zones: make(map[string]*time.Location)}
+
+func GetTimezone(lat, lng float64) (*Timezone, error) {
+	return GetTimezoneContext(context.Background(), lat, lng)
+}
+
+// GetTimezoneContext is like GetTimezone but uses ctx for the upstream request.
+// Zones are cached per ~100m cell and offsets are computed at call time so DST changes apply.
+func GetTimezoneContext(ctx context.Context, lat, lng float64) (*Timezone, error) {
+	if err := (Coordinate{Lat: lat, Lng: lng}).validate(); err != nil {
+		return nil, err
+	}
+	key := strconv.FormatFloat(lat, 'f', 3, 64) + "," + strconv.FormatFloat(lng, 'f', 3, 64)
+	timezoneCache.Lock()
+	location, ok := timezoneCache.zones[key]
+	timezoneCache.Unlock()
+	if !ok {
+		name, err := lookupTimezone(ctx, lat, lng)
+		if err != nil {
+			return nil, fmt.Errorf("lookupTimezone error: %w", err)
+		}
+		location, err = time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+		}
+		timezoneCache.Lock()
+		if len(timezoneCache.zones) >= timezoneCacheSize {
+			timezoneCache.zones = make(map[string]*time.Location)
+		}
+		timezoneCache.zones[key] = location
+		timezoneCache.Unlock()
+	}
+	shortName, offset := time.Now().In(location).Zone()
+	return &Timezone{
+		Name:          location.String(),
+		ShortName:     shortName,
+		OffsetSeconds: offset,
+		Location:      location,
+	}, nil
+}
+
+// lookupTimezone returns the IANA zone name at the coordinates
+func lookupTimezone(ctx context.Context, lat, lng float64) (string, error) {
+	params := url.Values{}
+	params.Set("key", locationIQAccessToken)
+	params.Set("format", "json")
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', -1, 64))
+	resp, err := timezoneClient.get(ctx, EndpointTimezone, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+	var result timezoneResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Timezone.Name == "" {
+		return "", fmt.Errorf("no time zone found")
+	}
+	return result.Timezone.Name, nil
+}
+
+// attachTimezones sets Timezone on the cities when requested by WithTimezone,
+// returning the last error while still filling the others
+func (o *SearchOptions) attachTimezones(ctx context.Context, cities ...*OsmCity) error {
+	if o == nil || !o.Timezone {
+		return nil
+	}
+	var globalErr error
+	for _, city := range cities {
+		timezone, err := GetTimezoneContext(ctx, city.Lat, city.Lng)
+		if err != nil {
+			globalErr = fmt.Errorf("GetTimezone error: %w", err)
+			continue
+		}
+		city.Timezone = timezone
+	}
+	return globalErr
+}
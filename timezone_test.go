@@ -0,0 +1,66 @@
+package posm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetTimezone(t *testing.T) {
+	if _, err := time.LoadLocation("America/Los_Angeles"); err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	var timezoneCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/timezone":
+			atomic.AddInt32(&timezoneCalls, 1)
+			if r.URL.Query().Get("lat") == "1" {
+				_, _ = fmt.Fprint(w, `{"timezone":{"name":"Mars/Olympus_Mons","offset_sec":0}}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"timezone":{"name":"America/Los_Angeles","now_in_dst":1,"offset_sec":-25200,"short_name":"PDT"}}`)
+		case "/search":
+			if r.URL.Query().Get("q") == "bad" {
+				_, _ = fmt.Fprint(w, `[ {"osm_type":"relation","osm_id":"2","display_name":"Nowhere","lat":"oops","lon":"-122.42","address":{"city":"Nowhere","state":"CA"}} ]`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `[ {"osm_type":"relation","osm_id":"1","display_name":"San Francisco","lat":"37.77","lon":"-122.42","address":{"city":"San Francisco","state":"CA"}} ]`)
+		}
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	tz, err := GetTimezone(37.7701, -122.4201)
+	if err != nil || tz.Name != "America/Los_Angeles" || tz.Location == nil {
+		t.Fatalf("GetTimezone failed: %+v %v", tz, err)
+	}
+	if tz.OffsetSeconds != -7*3600 && tz.OffsetSeconds != -8*3600 {
+		t.Fatalf("unexpected offset %d", tz.OffsetSeconds)
+	}
+	if _, err := GetTimezone(37.7701, -122.4201); err != nil || atomic.LoadInt32(&timezoneCalls) != 1 {
+		t.Fatalf("repeated lookups should be cached, got %d calls", timezoneCalls)
+	}
+
+	city, err := GetCityBySearch("sf", WithTimezone())
+	if err != nil || city.Timezone == nil || city.Timezone.Name != "America/Los_Angeles" {
+		t.Fatalf("WithTimezone should attach the zone: %+v %v", city, err)
+	}
+	if city, _ := GetCityBySearch("sf"); city.Timezone != nil {
+		t.Fatalf("time zone should only be attached on request")
+	}
+	calls := atomic.LoadInt32(&timezoneCalls)
+	if city, err := GetCityBySearch("bad", WithTimezone()); err == nil || city.Timezone != nil || atomic.LoadInt32(&timezoneCalls) != calls {
+		t.Fatalf("cities without coordinates should not get a time zone: %+v %v", city, err)
+	}
+
+	if _, err := GetTimezone(1, 1); err == nil {
+		t.Fatalf("unknown zone names should be rejected")
+	}
+	if _, err := GetTimezone(91, 0); err == nil {
+		t.Fatalf("invalid coordinates should be rejected")
+	}
+}
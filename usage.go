@@ -0,0 +1,284 @@
+package posm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Endpoint names used for usage accounting
+const (
+	EndpointSearch       = "search"
+	EndpointAutocomplete = "autocomplete"
+	EndpointLookup       = "lookup"
+	EndpointReverse      = "reverse"
+	EndpointNearby       = "nearby"
+	EndpointTimezone     = "timezone"
+	EndpointDirections   = "directions"
+	EndpointMatrix       = "matrix"
+)
+
+func isKnownEndpoint(endpoint string) bool {
+	switch endpoint {
+	case EndpointSearch, EndpointAutocomplete, EndpointLookup, EndpointReverse, EndpointNearby, EndpointTimezone, EndpointDirections, EndpointMatrix:
+		return true
+	}
+	return false
+}
+
+// ErrBudgetExceeded is matched by every BudgetExceededError via errors.Is
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// BudgetExceededError is returned when a call would go over the daily or monthly budget
+type BudgetExceededError struct {
+	Period string
+	Limit  int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s usage budget of %d requests exceeded", e.Period, e.Limit)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// Usage is a snapshot of the upstream calls counted by a UsageTracker.
+// Endpoint and caller counters cover the current day only.
+type Usage struct {
+	Day          string           `json:"day"`
+	Month        string           `json:"month"`
+	DailyTotal   int64            `json:"daily_total"`
+	MonthlyTotal int64            `json:"monthly_total"`
+	Endpoints    map[string]int64 `json:"endpoints"`
+	Callers      map[string]int64 `json:"callers"`
+}
+
+// UsageTracker counts upstream calls and rejects them once a budget is exhausted
+type UsageTracker struct {
+	mu           sync.Mutex
+	path         string
+	dailyLimit   int64
+	monthlyLimit int64
+	now          func() time.Time
+	usage        Usage
+	// saveInterval batches writes of the usage file, zero writes it on every call
+	saveInterval time.Duration
+	// saveTimer is set while a batched write of the usage file is pending
+	saveTimer *time.Timer
+	saveErr   error
+}
+
+var usageTracker *UsageTracker
+
+// NewUsageTracker creates a tracker persisted to path, restoring any counters already there.
+// An empty path keeps counters in memory only, and a zero limit disables that budget.
+func NewUsageTracker(path string, dailyLimit, monthlyLimit int64) (*UsageTracker, error) {
+	if dailyLimit < 0 || monthlyLimit < 0 {
+		return nil, fmt.Errorf("usage limits must not be negative")
+	}
+	t := &UsageTracker{
+		path:         path,
+		dailyLimit:   dailyLimit,
+		monthlyLimit: monthlyLimit,
+		now:          func() time.Time { return time.Now().UTC() },
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read usage file: %w", err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &t.usage); err != nil {
+				return nil, fmt.Errorf("failed to decode usage file: %w", err)
+			}
+		}
+	}
+	return t, nil
+}
+
+// SetUsageTracker makes all upstream calls count against the tracker, nil disables tracking
+func SetUsageTracker(t *UsageTracker) {
+	usageTracker = t
+}
+
+// Usage returns a copy of the current counters
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	usage := t.usage
+	usage.Endpoints = copyCounters(t.usage.Endpoints)
+	usage.Callers = copyCounters(t.usage.Callers)
+	return usage
+}
+
+// SetSaveInterval batches writes of the usage file, so busy callers do not rewrite it on every call.
+// Calls counted since the last write are lost if the process exits without Flush, so the file is
+// still written on every call once 90% of a budget is used. Zero, the default, writes on every call.
+func (t *UsageTracker) SetSaveInterval(interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.saveInterval = interval
+}
+
+// Flush writes pending counters to the usage file, call it before the process exits when batching writes.
+// It also reports a failure of an earlier write.
+func (t *UsageTracker) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.saveTimer != nil {
+		t.saveTimer.Stop()
+		t.saveTimer = nil
+	}
+	err := t.save()
+	if err == nil {
+		err, t.saveErr = t.saveErr, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist usage: %w", err)
+	}
+	return nil
+}
+
+// reserve records one call to endpoint, or fails without counting it if a budget is exhausted.
+// Call release if the request is not sent after all.
+func (t *UsageTracker) reserve(endpoint, caller string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	if t.dailyLimit > 0 && t.usage.DailyTotal >= t.dailyLimit {
+		return &BudgetExceededError{Period: "daily", Limit: t.dailyLimit}
+	}
+	if t.monthlyLimit > 0 && t.usage.MonthlyTotal >= t.monthlyLimit {
+		return &BudgetExceededError{Period: "monthly", Limit: t.monthlyLimit}
+	}
+	t.usage.DailyTotal++
+	t.usage.MonthlyTotal++
+	if t.usage.Endpoints == nil {
+		t.usage.Endpoints = make(map[string]int64)
+	}
+	t.usage.Endpoints[endpoint]++
+	if caller != "" {
+		if t.usage.Callers == nil {
+			t.usage.Callers = make(map[string]int64)
+		}
+		t.usage.Callers[caller]++
+	}
+	t.persist()
+	return nil
+}
+
+// release takes back a reserve for a request that was never sent
+func (t *UsageTracker) release(endpoint, caller string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.usage.DailyTotal > 0 {
+		t.usage.DailyTotal--
+	}
+	if t.usage.MonthlyTotal > 0 {
+		t.usage.MonthlyTotal--
+	}
+	if t.usage.Endpoints[endpoint] > 0 {
+		t.usage.Endpoints[endpoint]--
+	}
+	if caller != "" && t.usage.Callers[caller] > 0 {
+		t.usage.Callers[caller]--
+	}
+	t.persist()
+}
+
+// persist writes the counters right away, or batches the write when a save interval is set
+// and no budget is close to running out. A failed write is reported by Flush.
+func (t *UsageTracker) persist() {
+	if t.path == "" {
+		return
+	}
+	if t.saveInterval <= 0 || nearLimit(t.usage.DailyTotal, t.dailyLimit) || nearLimit(t.usage.MonthlyTotal, t.monthlyLimit) {
+		if t.saveTimer != nil {
+			t.saveTimer.Stop()
+			t.saveTimer = nil
+		}
+		if err := t.save(); err != nil {
+			t.saveErr = err
+		}
+		return
+	}
+	if t.saveTimer != nil {
+		return
+	}
+	t.saveTimer = time.AfterFunc(t.saveInterval, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.saveTimer = nil
+		if err := t.save(); err != nil {
+			t.saveErr = err
+		}
+	})
+}
+
+// nearLimit reports whether total has used 90% of a budget, zero limits are never near
+func nearLimit(total, limit int64) bool {
+	return limit > 0 && total*10 >= limit*9
+}
+
+// rollover resets the counters when the day or month has changed
+func (t *UsageTracker) rollover() {
+	now := t.now()
+	day := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+	if t.usage.Month != month {
+		t.usage.Month = month
+		t.usage.MonthlyTotal = 0
+	}
+	if t.usage.Day != day {
+		t.usage.Day = day
+		t.usage.DailyTotal = 0
+		t.usage.Endpoints = nil
+		t.usage.Callers = nil
+	}
+}
+
+// save writes the counters through a temporary file so a crash never leaves a partial file
+func (t *UsageTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(t.usage)
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+func copyCounters(counters map[string]int64) map[string]int64 {
+	if counters == nil {
+		return nil
+	}
+	result := make(map[string]int64, len(counters))
+	for k, v := range counters {
+		result[k] = v
+	}
+	return result
+}
+
+type callerTagKey struct{}
+
+// WithCallerTag attaches a caller tag used to attribute upstream calls made with ctx
+func WithCallerTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, callerTagKey{}, tag)
+}
+
+// CallerTag returns the caller tag attached to ctx, if any
+func CallerTag(ctx context.Context) string {
+	tag, _ := ctx.Value(callerTagKey{}).(string)
+	return tag
+}
@@ -0,0 +1,197 @@
+package posm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTrackerBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[ {"place_id":"1","display_name":"City","lat":"1","lon":"2","address":{"city":"SF","state":"CA"}} ]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := NewUsageTracker(path, 2, 0)
+	if err != nil {
+		t.Fatalf("NewUsageTracker error: %v", err)
+	}
+	SetUsageTracker(tracker)
+	defer SetUsageTracker(nil)
+
+	ctx := WithCallerTag(context.Background(), "checkout")
+	if _, err := GetCityBySearchContext(ctx, "sf"); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if _, err := GetCitiesByAutocompleteContext(ctx, "sf"); err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	_, err = GetCityBySearchContext(ctx, "sf")
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Period != "daily" || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("third call should exceed daily budget, got %v", err)
+	}
+
+	usage := tracker.Usage()
+	if usage.DailyTotal != 2 || usage.Endpoints[EndpointSearch] != 1 || usage.Endpoints[EndpointAutocomplete] != 1 || usage.Callers["checkout"] != 2 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	// counters survive a restart once flushed
+	if err := tracker.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	restored, err := NewUsageTracker(path, 2, 0)
+	if err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if err := restored.reserve(EndpointLookup, ""); err == nil {
+		t.Fatalf("restored tracker should still enforce the budget")
+	}
+
+	// a new day resets the daily budget but not the monthly total
+	restored.now = func() time.Time { return time.Now().UTC().Add(24 * time.Hour) }
+	if err := restored.reserve(EndpointLookup, ""); err != nil {
+		t.Fatalf("budget should reset on a new day: %v", err)
+	}
+	if err := restored.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+}
+
+func TestUsageTrackerMonthlyBudget(t *testing.T) {
+	tracker, err := NewUsageTracker("", 0, 1)
+	if err != nil {
+		t.Fatalf("NewUsageTracker error: %v", err)
+	}
+	if err := tracker.reserve(EndpointSearch, ""); err != nil {
+		t.Fatalf("first reserve failed: %v", err)
+	}
+	var budgetErr *BudgetExceededError
+	if err := tracker.reserve(EndpointSearch, ""); !errors.As(err, &budgetErr) || budgetErr.Period != "monthly" {
+		t.Fatalf("expected monthly budget error, got %v", err)
+	}
+	if _, err := NewUsageTracker("", -1, 0); err == nil {
+		t.Fatalf("negative limits should be rejected")
+	}
+}
+
+func TestUsageTrackerWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := NewUsageTracker(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewUsageTracker error: %v", err)
+	}
+	// every call is written through by default, so a crash loses nothing
+	if err := tracker.reserve(EndpointSearch, ""); err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	restored, err := NewUsageTracker(path, 0, 0)
+	if err != nil || restored.Usage().DailyTotal != 1 {
+		t.Fatalf("counters should be written on every call: %+v %v", restored.Usage(), err)
+	}
+
+	// batched writes wait for the interval or Flush
+	tracker.SetSaveInterval(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := tracker.reserve(EndpointSearch, ""); err != nil {
+			t.Fatalf("reserve failed: %v", err)
+		}
+	}
+	if restored, _ := NewUsageTracker(path, 0, 0); restored.Usage().DailyTotal != 1 {
+		t.Fatalf("batched calls should not be written yet, got %d", restored.Usage().DailyTotal)
+	}
+	if err := tracker.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if restored, _ := NewUsageTracker(path, 0, 0); restored.Usage().DailyTotal != 3 {
+		t.Fatalf("flushed counters should be restored, got %d", restored.Usage().DailyTotal)
+	}
+
+	// a failed write is reported instead of rejecting calls
+	tracker.path = filepath.Join(path, "missing", "usage.json")
+	if err := tracker.reserve(EndpointSearch, ""); err != nil {
+		t.Fatalf("reserve should not depend on the file: %v", err)
+	}
+	if err := tracker.Flush(); err == nil {
+		t.Fatalf("Flush should report write failures")
+	}
+}
+
+func TestUsageTrackerWritesNearLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := NewUsageTracker(path, 10, 0)
+	if err != nil {
+		t.Fatalf("NewUsageTracker error: %v", err)
+	}
+	tracker.SetSaveInterval(time.Hour)
+	defer tracker.Flush()
+	for i := 0; i < 9; i++ {
+		if err := tracker.reserve(EndpointSearch, ""); err != nil {
+			t.Fatalf("reserve failed: %v", err)
+		}
+	}
+	// the ninth call uses 90% of the daily budget and is written through
+	restored, err := NewUsageTracker(path, 10, 0)
+	if err != nil || restored.Usage().DailyTotal != 9 {
+		t.Fatalf("calls near the budget should be written through: %+v %v", restored.Usage(), err)
+	}
+}
+
+func TestBudgetIsCheckedBeforeRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+	limiter = newRateLimiter(0.001)
+	defer func() { limiter = nil }()
+	tracker, _ := NewUsageTracker("", 1, 0)
+	SetUsageTracker(tracker)
+	defer SetUsageTracker(nil)
+
+	_, _ = GetPointsBySearch("first")
+	// waiting for the taken slot would run into the timeout instead of the budget error
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := GetPointsBySearchContext(ctx, "second"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("the second call should exceed the budget, got %v", err)
+	}
+	limiter.mu.Lock()
+	wait := time.Until(limiter.next)
+	limiter.mu.Unlock()
+	if wait > time.Duration(float64(time.Second)/0.001) {
+		t.Fatalf("a call rejected by the budget should not take a rate limit slot, next slot in %v", wait)
+	}
+}
+
+func TestCancelledRequestIsNotCounted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	setupClientsForServer(server)
+	limiter = newRateLimiter(0.001)
+	defer func() { limiter = nil }()
+	tracker, _ := NewUsageTracker("", 0, 0)
+	SetUsageTracker(tracker)
+	defer SetUsageTracker(nil)
+
+	// the first call takes the only token, the second waits for the limiter and is cancelled
+	_, _ = GetPointsBySearch("first")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := GetPointsBySearchContext(ctx, "second"); err == nil {
+		t.Fatalf("the cancelled call should fail")
+	}
+	if usage := tracker.Usage(); usage.DailyTotal != 1 {
+		t.Fatalf("only the sent call should be counted, got %d", usage.DailyTotal)
+	}
+}
@@ -2,9 +2,13 @@ package posm
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"strings"
 )
 
+const earthRadiusMeters = 6371008.8
+
 type PlaceType uint8
 
 const (
@@ -32,3 +36,39 @@ func ContructPlaceID(address string, lat, lng float64) string {
 	addressName := strings.ReplaceAll(address, " ", "_")
 	return fmt.Sprintf("%s_%f_%f", addressName, lat, lng)
 }
+
+func setIfNotEmpty(params url.Values, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		params.Set(key, value)
+	}
+}
+
+// distanceMeters returns the great-circle distance between two coordinates
+func distanceMeters(a, b Coordinate) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// searchLead returns the lowercased first word of a search text, e.g. "1" for "1 Pariser Platz, Berlin"
+func searchLead(text string) string {
+	lead, _, _ := strings.Cut(text, ",")
+	words := strings.Fields(strings.ToLower(lead))
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}